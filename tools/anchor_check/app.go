@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/airframe"
+	"github.com/plane-watch/pw-silhouettes/internal/inkscape"
+	"github.com/plane-watch/pw-silhouettes/internal/jsonedit"
+	"github.com/plane-watch/pw-silhouettes/internal/raster"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+const canvasPx = 70
+
+func runApp(_ context.Context, cmd *cli.Command) error {
+
+	airframes, err := airframe.FromDir(cmd.String("airframes_path"))
+	if err != nil {
+		return err
+	}
+
+	// aliases share their canonical airframe's anchor
+	var canonical []*airframe.Airframe
+	for _, af := range airframes {
+		if af.AliasOf == nil {
+			canonical = append(canonical, af)
+		}
+	}
+
+	if want := cmd.StringSlice("airframe"); len(want) > 0 {
+		var selected []*airframe.Airframe
+		for _, d := range want {
+			i := slices.IndexFunc(canonical, func(af *airframe.Airframe) bool {
+				return strings.EqualFold(af.ICAO.Designator, d)
+			})
+			if i < 0 {
+				return fmt.Errorf("no canonical airframe with designator %q", d)
+			}
+			selected = append(selected, canonical[i])
+		}
+		canonical = selected
+	}
+
+	opts := checkOptions{
+		InkscapeBinary:      cmd.String("inkscape_binary"),
+		Zoom:                int(cmd.Int("zoom")),
+		RotorWidth:          cmd.Float("rotor_width"),
+		CentrelineTolerance: cmd.Float("centreline_tolerance"),
+		MaxAsymmetry:        cmd.Float("max_asymmetry"),
+	}
+	if opts.Zoom < 1 {
+		return fmt.Errorf("zoom must be >= 1")
+	}
+
+	issues, written := 0, 0
+	for _, af := range canonical {
+		a, err := analyse(af, opts)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", af.ICAO.Designator, err)
+		}
+		n := check(af, a, opts)
+		issues += n
+		if n == 0 || !cmd.Bool("write") {
+			continue
+		}
+		if err := writeAnchor(af.File, a.Suggested); err != nil {
+			return fmt.Errorf("failed to write anchor for %s: %w", af.ICAO.Designator, err)
+		}
+		log.Info().
+			Str("airframe", af.ICAO.Designator).
+			Str("file", af.File).
+			Msgf("anchor set to (%d,%d)", a.Suggested.X, a.Suggested.Y)
+		written++
+	}
+
+	if issues > 0 && written == 0 {
+		return fmt.Errorf("%d issues", issues)
+	}
+	return nil
+}
+
+type checkOptions struct {
+	InkscapeBinary      string
+	Zoom                int
+	RotorWidth          float64
+	CentrelineTolerance float64
+	MaxAsymmetry        float64
+}
+
+// analysis is what's measured from an airframe's silhouette. Positions are in px within the
+// 70×70 artwork.
+type analysis struct {
+	Body                 *raster.Mask // the filled silhouette, without rotors, at zoom
+	Zoom                 int
+	CentroidX, CentroidY float64
+	Centreline           float64 // x of the fuselage centreline
+	Asymmetry            float64 // the fraction of the silhouette that isn't mirrored across the centreline
+	HasCentreline        bool
+	Suggested            airframe.Anchor
+}
+
+// analyse renders an airframe's first frame and measures its silhouette.
+//
+// The silhouette is filled in, so outlines count as solid shapes. For rotorcraft, anything narrower
+// than the rotor width is left out, as the blades would otherwise drag the centroid around.
+//
+// Airframes point north, so the fuselage centreline is the vertical line the silhouette is most
+// nearly mirrored across. Airframes that aren't symmetric enough (or don't rotate) have no centreline.
+//
+// The suggested anchor is on the centreline (or the centroid, without one), level with the centroid,
+// moved to the nearest pixel of the silhouette if that's outside it.
+func analyse(af *airframe.Airframe, opts checkOptions) (*analysis, error) {
+	size := canvasPx * opts.Zoom
+	img, err := inkscape.Render(opts.InkscapeBinary, af.Art.Frames[0].Src, size)
+	if err != nil {
+		return nil, err
+	}
+	body := raster.MaskFrom(img).FillHoles()
+	if strings.HasPrefix(af.ICAO.TypeCode, "H") || strings.HasPrefix(af.ICAO.TypeCode, "G") {
+		if opened := body.Open(int(math.Ceil(opts.RotorWidth * float64(opts.Zoom) / 2))); opened.Count() > 0 {
+			body = opened
+		}
+	}
+	n := body.Count()
+	if n == 0 {
+		return nil, fmt.Errorf("%s has nothing visible", af.Art.Frames[0].Src)
+	}
+
+	a := &analysis{Body: body, Zoom: opts.Zoom}
+	zoom := float64(opts.Zoom)
+	sumX, sumY := 0.0, 0.0
+	for y := 0; y < body.H; y++ {
+		for x := 0; x < body.W; x++ {
+			if body.On[y*body.W+x] {
+				sumX += float64(x) + 0.5
+				sumY += float64(y) + 0.5
+			}
+		}
+	}
+	a.CentroidX = sumX / float64(n) / zoom
+	a.CentroidY = sumY / float64(n) / zoom
+
+	axis, mismatched := mirrorAxis(body)
+	a.Centreline = axis / zoom
+	a.Asymmetry = float64(mismatched) / float64(n)
+	a.HasCentreline = !af.Render.NoRotate && a.Asymmetry <= opts.MaxAsymmetry
+
+	x := a.CentroidX
+	if a.HasCentreline {
+		x = a.Centreline
+	}
+	a.Suggested = a.nearestInside(int(math.Floor(x)), int(math.Floor(a.CentroidY)))
+	return a, nil
+}
+
+// mirrorAxis finds the vertical line (in zoomed px, to the nearest half pixel) that m is most nearly
+// mirrored across, and how many of its pixels aren't mirrored across it. Lines nearer the middle
+// are preferred when there's a tie.
+func mirrorAxis(m *raster.Mask) (axis float64, mismatched int) {
+	best, bestK := -1, 0
+	// the line is at k/2, so pixel x mirrors onto pixel k-x-1
+	for k := m.W / 2; k <= m.W*3/2; k++ {
+		n := 0
+		for y := 0; y < m.H; y++ {
+			for x := 0; x < m.W; x++ {
+				if m.On[y*m.W+x] && !m.At(k-x-1, y) {
+					n++
+				}
+			}
+		}
+		if best < 0 || n < best || (n == best && abs(k-m.W) < abs(bestK-m.W)) {
+			best, bestK = n, k
+		}
+	}
+	return float64(bestK) / 2, best
+}
+
+// inside returns whether the middle of the artwork pixel (x,y) is within the silhouette.
+func (a *analysis) inside(x, y int) bool {
+	return a.Body.At(x*a.Zoom+a.Zoom/2, y*a.Zoom+a.Zoom/2)
+}
+
+// nearestInside returns the artwork pixel within the silhouette that's closest to (x,y), preferring
+// pixels in the same column.
+func (a *analysis) nearestInside(x, y int) airframe.Anchor {
+	if a.inside(x, y) {
+		return airframe.Anchor{X: x, Y: y}
+	}
+	best, bestD := airframe.Anchor{X: x, Y: y}, math.Inf(1)
+	for py := 0; py < canvasPx; py++ {
+		for px := 0; px < canvasPx; px++ {
+			if !a.inside(px, py) {
+				continue
+			}
+			// moving sideways, off the centreline, costs more than moving along it
+			d := math.Hypot(4*float64(px-x), float64(py-y))
+			if d < bestD {
+				best, bestD = airframe.Anchor{X: px, Y: py}, d
+			}
+		}
+	}
+	return best
+}
+
+// check logs what was measured, and each problem with the declared anchor, returning the number of problems.
+func check(af *airframe.Airframe, a *analysis, opts checkOptions) int {
+	logger := log.With().
+		Str("airframe", af.ICAO.Designator).
+		Str("src", af.Art.Frames[0].Src).
+		Logger()
+
+	ev := logger.Info().
+		Str("centroid", fmt.Sprintf("(%.1f,%.1f)", a.CentroidX, a.CentroidY)).
+		Float64("asymmetry_pct", math.Round(1000*a.Asymmetry)/10)
+	if a.HasCentreline {
+		ev = ev.Float64("centreline", a.Centreline)
+	}
+	ev.Str("suggested", fmt.Sprintf("(%d,%d)", a.Suggested.X, a.Suggested.Y)).Msg("measured silhouette")
+
+	anchor := af.Render.Anchor
+	issues := 0
+	if !a.inside(anchor.X, anchor.Y) {
+		logger.Error().Msgf("anchor (%d,%d) is outside the silhouette, suggest (%d,%d)",
+			anchor.X, anchor.Y, a.Suggested.X, a.Suggested.Y)
+		issues++
+	}
+	if d := math.Abs(float64(anchor.X) + 0.5 - a.Centreline); a.HasCentreline && d > opts.CentrelineTolerance {
+		logger.Error().Msgf("anchor (%d,%d) is %.1fpx off the centreline at x=%g (tolerance %gpx), suggest (%d,%d)",
+			anchor.X, anchor.Y, d, a.Centreline, opts.CentrelineTolerance, a.Suggested.X, a.Suggested.Y)
+		issues++
+	}
+	return issues
+}
+
+// writeAnchor sets render.anchor in an airframe's JSON file, leaving the rest of the file as it is.
+func writeAnchor(filename string, anchor airframe.Anchor) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read airframe: %w", err)
+	}
+	if data, err = jsonedit.Set(data, anchor.X, "render", "anchor", "x"); err != nil {
+		return err
+	}
+	if data, err = jsonedit.Set(data, anchor.Y, "render", "anchor", "y"); err != nil {
+		return err
+	}
+	if err := svgdoc.WriteFileAtomic(filename, data); err != nil {
+		return fmt.Errorf("failed to write airframe: %w", err)
+	}
+	return nil
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:   "anchor_check",
+	Usage:  "Suggest an anchor for each airframe from its silhouette, and check that declared anchors are on the fuselage",
+	Action: runApp,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "airframes_path",
+			Aliases: []string{"afp"},
+			Usage:   "Path to the airframes JSON directory",
+			Value:   "airframes/",
+		},
+		&cli.StringSliceFlag{
+			Name:  "airframe",
+			Usage: "Designator of an airframe to check (default: all canonical airframes)",
+		},
+		&cli.StringFlag{
+			Name:     "inkscape_binary",
+			Aliases:  []string{"inkscape"},
+			Usage:    "Path to the inkscape v1+ binary",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "zoom",
+			Usage: "Render silhouettes at this multiple of the 70px canvas, for sub-pixel precision",
+			Value: 4,
+		},
+		&cli.FloatFlag{
+			Name:  "rotor_width",
+			Usage: "Rotor blades and propellers are assumed to be narrower than this many px, and are left out of the silhouette",
+			Value: 3,
+		},
+		&cli.FloatFlag{
+			Name:  "centreline_tolerance",
+			Usage: "Maximum distance in px that an anchor may be from the fuselage centreline",
+			Value: 1,
+		},
+		&cli.FloatFlag{
+			Name:  "max_asymmetry",
+			Usage: "Silhouettes whose halves differ by more than this fraction of their area have no centreline, and aren't checked against one",
+			Value: 0.1,
+		},
+		&cli.BoolFlag{
+			Name:  "write",
+			Usage: "Write the suggested anchor to each airframe that fails a check",
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/airframe"
+	"github.com/plane-watch/pw-silhouettes/internal/spritesheet"
+)
+
+//go:embed catalog.html.tmpl
+var catalogTemplate string
+
+type (
+	// catalog is the data behind catalog.html
+	catalog struct {
+		Sheet    string         `json:"sheet"`    // the spritesheet PNG, as a data: URL
+		CellSize int            `json:"cellSize"` // size of the artwork within each sprite
+		Entries  []catalogEntry `json:"entries"`
+	}
+
+	// catalogEntry is an airframe with its own sprite
+	catalogEntry struct {
+		Designator   string             `json:"designator"`
+		TypeCode     string             `json:"typeCode"`
+		WakeCategory string             `json:"wakeCategory"`
+		Aliases      []string           `json:"aliases"` // designators drawn with this airframe's sprite
+		Sprite       spritesheet.Sprite `json:"sprite"`
+		Frames       []catalogFrame     `json:"frames"`
+
+		// Airframe is the airframe's descriptive metadata, if it has any
+		Airframe *spritesheet.Airframe `json:"airframe,omitempty"`
+	}
+
+	// catalogFrame is the top-left of a frame's artwork in the spritesheet
+	catalogFrame struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+)
+
+// writeCatalog writes a self-contained HTML page showing every airframe's sprite, cut from the final
+// spritesheet, alongside its metadata.
+func writeCatalog(filename string, out *spritesheet.Output, airframes []*airframe.Airframe, sheetPNG []byte, sheetWidth int) error {
+	tmpl, err := template.New("catalog").Parse(catalogTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse catalog template: %w", err)
+	}
+
+	aliases := make(map[string][]string)
+	for _, af := range airframes {
+		if af.AliasOf != nil {
+			aliases[*af.AliasOf] = append(aliases[*af.AliasOf], af.ICAO.Designator)
+		}
+	}
+
+	c := catalog{
+		Sheet:    "data:image/png;base64," + base64.StdEncoding.EncodeToString(sheetPNG),
+		CellSize: spritesheet.SpriteWidth - 2,
+	}
+	for _, af := range airframes {
+		s, ok := out.Sprites[af.ICAO.Designator]
+		if !ok {
+			continue
+		}
+		e := catalogEntry{
+			Designator:   af.ICAO.Designator,
+			TypeCode:     af.ICAO.TypeCode,
+			WakeCategory: af.ICAO.WakeCategory,
+			Aliases:      aliases[af.ICAO.Designator],
+			Sprite:       s,
+		}
+		if a, ok := out.Airframes[af.ICAO.Designator]; ok {
+			e.Airframe = &a
+		}
+		slices.Sort(e.Aliases)
+		for _, id := range s.IDs {
+			x, y, err := spritesheet.TopLeft(id, sheetWidth, spritesheet.SpriteWidth, spritesheet.SpriteHeight, 0, 0)
+			if err != nil {
+				return fmt.Errorf("failed to get top left: %w", err)
+			}
+			// the artwork is drawn 1px in from the sprite's top-left
+			e.Frames = append(e.Frames, catalogFrame{X: x + 1, Y: y + 1})
+		}
+		c.Entries = append(c.Entries, e)
+	}
+	slices.SortFunc(c.Entries, func(a, b catalogEntry) int {
+		return strings.Compare(a.Designator, b.Designator)
+	})
+
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, c); err != nil {
+		return fmt.Errorf("failed to render catalog: %w", err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	return nil
+}
@@ -26,6 +26,10 @@ var cmd = &cli.Command{
 			Usage:    "Path to the inkscape v1+ binary",
 			Required: true,
 		},
+		&cli.StringFlag{
+			Name:  "scale_curve",
+			Usage: "Path to the scale curve used to derive render.scale from wingspan_m and length_m (default: render_scale.json beside the airframes directory, skipped with a warning if it doesn't exist)",
+		},
 		&cli.StringFlag{
 			Name:     "output_png",
 			Aliases:  []string{"op"},
@@ -38,6 +42,11 @@ var cmd = &cli.Command{
 			Usage:    "Path to the output json file",
 			Required: true,
 		},
+		&cli.StringFlag{
+			Name:    "output_catalog",
+			Aliases: []string{"oc"},
+			Usage:   "Path to write an HTML catalogue of the sprites to (optional)",
+		},
 	},
 }
 
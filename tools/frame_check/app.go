@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/airframe"
+	"github.com/plane-watch/pw-silhouettes/internal/inkscape"
+	"github.com/plane-watch/pw-silhouettes/internal/raster"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	canvasPx = 70
+
+	// maxMovePx is how far (in px) we search for a frame's fuselage having moved
+	maxMovePx = 4
+)
+
+func runApp(_ context.Context, cmd *cli.Command) error {
+
+	airframes, err := airframe.FromDir(cmd.String("airframes_path"))
+	if err != nil {
+		return err
+	}
+
+	// only canonical airframes with more than one frame are animated
+	var animated []*airframe.Airframe
+	for _, af := range airframes {
+		if af.AliasOf == nil && len(af.Art.Frames) > 1 {
+			animated = append(animated, af)
+		}
+	}
+
+	if want := cmd.StringSlice("airframe"); len(want) > 0 {
+		var selected []*airframe.Airframe
+		for _, d := range want {
+			i := slices.IndexFunc(animated, func(af *airframe.Airframe) bool {
+				return strings.EqualFold(af.ICAO.Designator, d)
+			})
+			if i < 0 {
+				return fmt.Errorf("no animated airframe with designator %q", d)
+			}
+			selected = append(selected, animated[i])
+		}
+		animated = selected
+	}
+
+	if dir := cmd.String("diff_dir"); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create diff dir: %w", err)
+		}
+	}
+
+	opts := checkOptions{
+		InkscapeBinary: cmd.String("inkscape_binary"),
+		Zoom:           int(cmd.Int("zoom")),
+		MoveTolerance:  cmd.Float("move_tolerance"),
+		BBoxTolerance:  cmd.Float("bbox_tolerance"),
+		RotorWidth:     cmd.Float("rotor_width"),
+		DiffDir:        cmd.String("diff_dir"),
+	}
+	if opts.Zoom < 1 {
+		return fmt.Errorf("zoom must be >= 1")
+	}
+
+	issues := 0
+	for _, af := range animated {
+		n, err := checkFrames(af, opts)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", af.ICAO.Designator, err)
+		}
+		issues += n
+	}
+
+	if issues > 0 {
+		return fmt.Errorf("%d issues", issues)
+	}
+	return nil
+}
+
+type checkOptions struct {
+	InkscapeBinary string
+	Zoom           int
+	MoveTolerance  float64
+	BBoxTolerance  float64
+	RotorWidth     float64
+	DiffDir        string
+}
+
+// checkFrames compares every frame of an airframe against frame 1, returning the number of issues found.
+//
+// Rotors and propellers are the only things expected to change between frames, and they are thin.
+// Movement is measured on each frame with everything narrower than the rotor width removed, as the
+// offset that best aligns what's left with frame 1.
+//
+// Bounding boxes are compared over the non-rotor region: anything narrower than the rotor width in any
+// frame (plus a margin where blades cross the body) is the rotor region, shared by all frames, so any
+// difference left outside it is real.
+func checkFrames(af *airframe.Airframe, opts checkOptions) (int, error) {
+	size := canvasPx * opts.Zoom
+	zoom := float64(opts.Zoom)
+	radius := int(math.Ceil(opts.RotorWidth * zoom / 2))
+
+	var masks, opened []*raster.Mask
+	rotor := raster.NewMask(size, size)
+	for _, frame := range af.Art.Frames {
+		img, err := inkscape.Render(opts.InkscapeBinary, frame.Src, size)
+		if err != nil {
+			return 0, err
+		}
+		m := raster.MaskFrom(img)
+		o := m.Open(radius)
+		masks = append(masks, m)
+		opened = append(opened, o)
+		rotor = rotor.Or(m.AndNot(o))
+	}
+	rotor = rotor.Spread(radius, true)
+
+	var bodies []*raster.Mask
+	for _, m := range masks {
+		bodies = append(bodies, m.AndNot(rotor))
+	}
+
+	first, firstBody := masks[0], bodies[0]
+	firstBox := firstBody.BBox()
+	issues := 0
+
+	for i := 1; i < len(masks); i++ {
+		m, body := masks[i], bodies[i]
+		src := af.Art.Frames[i].Src
+		logger := log.With().
+			Str("airframe", af.ICAO.Designator).
+			Int("frame", i+1).
+			Str("src", src).
+			Logger()
+
+		diff := opened[0].Diff(opened[i], 0, 0)
+		dx, dy, aligned := bestShift(opened[0], opened[i], maxMovePx*opts.Zoom)
+		moved := math.Hypot(float64(dx), float64(dy)) / zoom
+
+		logger.Info().
+			Float64("diff_pct", 100*float64(diff)/float64(size*size)).
+			Float64("aligned_diff_pct", 100*float64(aligned)/float64(size*size)).
+			Float64("moved_px", moved).
+			Msg("compared frame with frame 1")
+
+		if moved > opts.MoveTolerance {
+			logger.Error().Msgf("frame %d has moved by (%.2g,%.2g)px relative to frame 1 (tolerance %gpx)", i+1, float64(dx)/zoom, float64(dy)/zoom, opts.MoveTolerance)
+			issues++
+		}
+
+		box := body.BBox()
+		if d := maxEdgeDistance(firstBox, box); float64(d)/zoom > opts.BBoxTolerance {
+			logger.Error().Msgf("frame %d bounding box %s differs from frame 1 %s by up to %.2gpx (tolerance %gpx)",
+				i+1, scaleRect(box, zoom), scaleRect(firstBox, zoom), float64(d)/zoom, opts.BBoxTolerance)
+			issues++
+		}
+
+		if opts.DiffDir != "" {
+			name := fmt.Sprintf("%s-%d-diff.png", af.ICAO.Designator, i+1)
+			if err := raster.WritePNG(filepath.Join(opts.DiffDir, name), diffImage(first, m, rotor)); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// diffImage shows where b differs from a: pixels only in a are blue, pixels only in b are red,
+// and pixels in both are grey. Within the rotor region the colours are paler.
+func diffImage(a, b, rotor *raster.Mask) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, a.W, a.H))
+	for y := 0; y < a.H; y++ {
+		for x := 0; x < a.W; x++ {
+			var c color.NRGBA
+			switch inA, inB := a.At(x, y), b.At(x, y); {
+			case inA && inB:
+				c = color.NRGBA{R: 0xa0, G: 0xa0, B: 0xa0, A: 0xff}
+			case inA:
+				c = color.NRGBA{B: 0xff, A: 0xff}
+			case inB:
+				c = color.NRGBA{R: 0xff, A: 0xff}
+			default:
+				c = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+			if rotor.At(x, y) {
+				c.A = 0x60
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// bestShift finds the offset (within ±max pixels) of o that best aligns it with m.
+// No offset is preferred when there is a tie.
+func bestShift(m, o *raster.Mask, max int) (dx, dy, diff int) {
+	diff = m.Diff(o, 0, 0)
+	for y := -max; y <= max; y++ {
+		for x := -max; x <= max; x++ {
+			if d := m.Diff(o, x, y); d < diff || (d == diff && abs(x)+abs(y) < abs(dx)+abs(dy)) {
+				dx, dy, diff = x, y, d
+			}
+		}
+	}
+	return dx, dy, diff
+}
+
+// maxEdgeDistance returns the furthest that any edge of b is from the same edge of a.
+func maxEdgeDistance(a, b image.Rectangle) int {
+	return max(abs(a.Min.X-b.Min.X), abs(a.Min.Y-b.Min.Y), abs(a.Max.X-b.Max.X), abs(a.Max.Y-b.Max.Y))
+}
+
+func scaleRect(r image.Rectangle, zoom float64) string {
+	return fmt.Sprintf("(%.4g,%.4g)-(%.4g,%.4g)", float64(r.Min.X)/zoom, float64(r.Min.Y)/zoom, float64(r.Max.X)/zoom, float64(r.Max.Y)/zoom)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:   "frame_check",
+	Usage:  "Check that the frames of animated airframes only differ in their rotors/propellers",
+	Action: runApp,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "airframes_path",
+			Aliases: []string{"afp"},
+			Usage:   "Path to the airframes JSON directory",
+			Value:   "airframes/",
+		},
+		&cli.StringSliceFlag{
+			Name:  "airframe",
+			Usage: "Designator of an airframe to check (default: all animated airframes)",
+		},
+		&cli.StringFlag{
+			Name:     "inkscape_binary",
+			Aliases:  []string{"inkscape"},
+			Usage:    "Path to the inkscape v1+ binary",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "zoom",
+			Usage: "Render frames at this multiple of the 70px canvas, for sub-pixel precision",
+			Value: 4,
+		},
+		&cli.FloatFlag{
+			Name:  "move_tolerance",
+			Usage: "Maximum distance in px that a frame's fuselage may move relative to frame 1",
+			Value: 0.5,
+		},
+		&cli.FloatFlag{
+			Name:  "bbox_tolerance",
+			Usage: "Maximum distance in px that any edge of a frame's bounding box may move relative to frame 1",
+			Value: 1,
+		},
+		&cli.FloatFlag{
+			Name:  "rotor_width",
+			Usage: "Rotor blades and propellers are assumed to be narrower than this many px, and are ignored when comparing frames",
+			Value: 3,
+		},
+		&cli.StringFlag{
+			Name:  "diff_dir",
+			Usage: "If set, write a difference mask PNG for each frame to this directory",
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// chunk is a PNG chunk, without its length and CRC.
+type chunk struct {
+	Type string
+	Data []byte
+}
+
+// readChunks splits an encoded PNG into its chunks.
+func readChunks(data []byte) ([]chunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("not a PNG")
+	}
+	data = data[len(pngSignature):]
+	var chunks []chunk
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, fmt.Errorf("truncated chunk")
+		}
+		n := int(binary.BigEndian.Uint32(data[:4]))
+		if len(data) < 12+n {
+			return nil, fmt.Errorf("truncated chunk")
+		}
+		chunks = append(chunks, chunk{Type: string(data[4:8]), Data: data[8 : 8+n]})
+		data = data[12+n:]
+	}
+	return chunks, nil
+}
+
+// writeChunk writes a PNG chunk, with its length and CRC.
+func writeChunk(w io.Writer, c chunk) error {
+	var head [8]byte
+	binary.BigEndian.PutUint32(head[:4], uint32(len(c.Data)))
+	copy(head[4:], c.Type)
+	crc := crc32.NewIEEE()
+	crc.Write(head[4:])
+	crc.Write(c.Data)
+	if _, err := w.Write(head[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(c.Data); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// encodeAPNG writes the frames as an animated PNG that loops forever, showing each frame for
+// frameTime milliseconds. All frames must be the same size.
+//
+// Each frame is encoded by image/png, and its image data is moved into the animation's frame
+// chunks. Browsers that don't support APNG show the first frame.
+func encodeAPNG(w io.Writer, frames []*image.NRGBA, frameTime int) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames")
+	}
+
+	var out bytes.Buffer
+	out.Write(pngSignature)
+
+	var ihdr []byte
+	seq := uint32(0)
+	for i, frame := range frames {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, frame); err != nil {
+			return fmt.Errorf("failed to encode frame %d: %w", i+1, err)
+		}
+		chunks, err := readChunks(buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to read frame %d: %w", i+1, err)
+		}
+
+		var idat [][]byte
+		for _, c := range chunks {
+			switch c.Type {
+			case "IHDR":
+				if ihdr == nil {
+					ihdr = c.Data
+					writeChunk(&out, c)
+					actl := make([]byte, 8)
+					binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+					binary.BigEndian.PutUint32(actl[4:], 0) // loop forever
+					writeChunk(&out, chunk{Type: "acTL", Data: actl})
+				} else if !bytes.Equal(ihdr, c.Data) {
+					// image/png picks the colour type from the pixels, so an opaque frame differs
+					return fmt.Errorf("frame %d doesn't encode like frame 1 (is it opaque?)", i+1)
+				}
+			case "IDAT":
+				idat = append(idat, c.Data)
+			}
+		}
+
+		b := frame.Bounds()
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(b.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(b.Dy()))
+		// x and y offsets are 0
+		binary.BigEndian.PutUint16(fctl[20:], uint16(frameTime))
+		binary.BigEndian.PutUint16(fctl[22:], 1000)
+		fctl[24] = 1 // dispose to transparent black, so transparent pixels don't show the last frame
+		fctl[25] = 0 // replace, rather than blend with, the previous frame
+		writeChunk(&out, chunk{Type: "fcTL", Data: fctl})
+		seq++
+
+		for _, data := range idat {
+			if i == 0 {
+				// the first frame is also the default image
+				writeChunk(&out, chunk{Type: "IDAT", Data: data})
+				continue
+			}
+			fdat := make([]byte, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, seq)
+			copy(fdat[4:], data)
+			writeChunk(&out, chunk{Type: "fdAT", Data: fdat})
+			seq++
+		}
+	}
+	writeChunk(&out, chunk{Type: "IEND"})
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
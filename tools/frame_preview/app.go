@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/airframe"
+	"github.com/plane-watch/pw-silhouettes/internal/inkscape"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+const canvasPx = 70
+
+func runApp(_ context.Context, cmd *cli.Command) error {
+
+	airframes, err := airframe.FromDir(cmd.String("airframes_path"))
+	if err != nil {
+		return err
+	}
+
+	// only canonical airframes with more than one frame are animated
+	var animated []*airframe.Airframe
+	for _, af := range airframes {
+		if af.AliasOf == nil && len(af.Art.Frames) > 1 {
+			animated = append(animated, af)
+		}
+	}
+
+	if want := cmd.StringSlice("airframe"); len(want) > 0 {
+		var selected []*airframe.Airframe
+		for _, d := range want {
+			i := slices.IndexFunc(animated, func(af *airframe.Airframe) bool {
+				return strings.EqualFold(af.ICAO.Designator, d)
+			})
+			if i < 0 {
+				return fmt.Errorf("no animated airframe with designator %q", d)
+			}
+			selected = append(selected, animated[i])
+		}
+		animated = selected
+	}
+
+	zooms := cmd.IntSlice("zoom")
+	for _, z := range zooms {
+		if z < 1 {
+			return fmt.Errorf("zoom must be >= 1")
+		}
+	}
+
+	dir := cmd.String("output_dir")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output dir: %w", err)
+	}
+
+	written := 0
+	for _, af := range animated {
+		if af.Art.FrameTime <= 0 || af.Art.FrameTime > 0xffff {
+			log.Warn().
+				Str("airframe", af.ICAO.Designator).
+				Int("frame_time", af.Art.FrameTime).
+				Msg("skipping animated airframe without a usable frameTime")
+			continue
+		}
+		for _, z := range zooms {
+			files, err := writePreviews(af, cmd.String("inkscape_binary"), dir, z)
+			if err != nil {
+				return fmt.Errorf("failed to preview %s: %w", af.ICAO.Designator, err)
+			}
+			log.Info().
+				Str("airframe", af.ICAO.Designator).
+				Int("frames", len(af.Art.Frames)).
+				Int("frame_time", af.Art.FrameTime).
+				Int("zoom", z).
+				Strs("files", files).
+				Msg("wrote previews")
+			written += len(files)
+		}
+	}
+
+	log.Info().
+		Int("airframes", len(animated)).
+		Int("files", written).
+		Str("output_dir", dir).
+		Msg("done")
+	return nil
+}
+
+// writePreviews renders the airframe's frames at the given zoom, and writes them as an APNG and a GIF.
+func writePreviews(af *airframe.Airframe, inkscapeBinary, dir string, zoom int) ([]string, error) {
+	frames := make([]*image.NRGBA, 0, len(af.Art.Frames))
+	for _, frame := range af.Art.Frames {
+		img, err := inkscape.Render(inkscapeBinary, frame.Src, canvasPx*zoom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", frame.Src, err)
+		}
+		frames = append(frames, toNRGBA(img))
+	}
+	for i, frame := range frames[1:] {
+		if frame.Rect != frames[0].Rect {
+			return nil, fmt.Errorf("%s is %dx%d, but %s is %dx%d",
+				af.Art.Frames[i+1].Src, frame.Rect.Dx(), frame.Rect.Dy(),
+				af.Art.Frames[0].Src, frames[0].Rect.Dx(), frames[0].Rect.Dy())
+		}
+	}
+
+	stem := filepath.Join(dir, fmt.Sprintf("%s-%dx", af.ICAO.Designator, zoom))
+
+	var apng bytes.Buffer
+	if err := encodeAPNG(&apng, frames, af.Art.FrameTime); err != nil {
+		return nil, fmt.Errorf("failed to encode APNG: %w", err)
+	}
+	if err := os.WriteFile(stem+".png", apng.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write APNG: %w", err)
+	}
+
+	var g bytes.Buffer
+	if err := gif.EncodeAll(&g, toGIF(frames, af.Art.FrameTime)); err != nil {
+		return nil, fmt.Errorf("failed to encode GIF: %w", err)
+	}
+	if err := os.WriteFile(stem+".gif", g.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write GIF: %w", err)
+	}
+
+	return []string{stem + ".png", stem + ".gif"}, nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok {
+		return n
+	}
+	n := image.NewNRGBA(img.Bounds())
+	draw.Draw(n, n.Rect, img, img.Bounds().Min, draw.Src)
+	return n
+}
+
+// toGIF converts the frames to a looping GIF. GIF transparency is all or nothing, so pixels that are
+// at least half transparent become transparent, and the rest keep their colour at full opacity.
+//
+// Silhouettes have few colours, so every frame shares a palette of exactly the colours used. If
+// there are too many, the colours are matched to the nearest in a standard palette instead.
+func toGIF(frames []*image.NRGBA, frameTime int) *gif.GIF {
+	opaque := func(c color.NRGBA) (color.NRGBA, bool) {
+		return color.NRGBA{R: c.R, G: c.G, B: c.B, A: 0xff}, c.A >= 0x80
+	}
+
+	// index 0 is transparent
+	pal := color.Palette{color.NRGBA{}}
+	index := map[color.NRGBA]uint8{}
+	for _, frame := range frames {
+		for i := 0; i < len(frame.Pix); i += 4 {
+			c, ok := opaque(color.NRGBA{R: frame.Pix[i], G: frame.Pix[i+1], B: frame.Pix[i+2], A: frame.Pix[i+3]})
+			if _, seen := index[c]; !ok || seen {
+				continue
+			}
+			if len(pal) == 256 {
+				pal = append(color.Palette{color.NRGBA{}}, palette.Plan9[:255]...)
+				index = nil
+				break
+			}
+			index[c] = uint8(len(pal))
+			pal = append(pal, c)
+		}
+		if index == nil {
+			break
+		}
+	}
+
+	// GIF delays are in 1/100s, and browsers slow down anything under 2
+	delay := max((frameTime+5)/10, 2)
+
+	out := &gif.GIF{LoopCount: 0}
+	for _, frame := range frames {
+		img := image.NewPaletted(frame.Rect, pal)
+		for y := frame.Rect.Min.Y; y < frame.Rect.Max.Y; y++ {
+			for x := frame.Rect.Min.X; x < frame.Rect.Max.X; x++ {
+				c, ok := opaque(frame.NRGBAAt(x, y))
+				if !ok {
+					continue
+				}
+				if i, found := index[c]; found {
+					img.SetColorIndex(x, y, i)
+				} else {
+					// the standard palette starts at index 1
+					img.SetColorIndex(x, y, uint8(pal[1:].Index(c)+1))
+				}
+			}
+		}
+		out.Image = append(out.Image, img)
+		out.Delay = append(out.Delay, delay)
+		out.Disposal = append(out.Disposal, gif.DisposalBackground)
+	}
+	return out
+}
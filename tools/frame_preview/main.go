@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:   "frame_preview",
+	Usage:  "Assemble the frames of animated airframes into APNG and GIF previews, played at their frameTime",
+	Action: runApp,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "airframes_path",
+			Aliases: []string{"afp"},
+			Usage:   "Path to the airframes JSON directory",
+			Value:   "airframes/",
+		},
+		&cli.StringSliceFlag{
+			Name:  "airframe",
+			Usage: "Designator of an airframe to preview (default: all animated airframes)",
+		},
+		&cli.StringFlag{
+			Name:     "inkscape_binary",
+			Aliases:  []string{"inkscape"},
+			Usage:    "Path to the inkscape v1+ binary",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "output_dir",
+			Usage: "Directory to write the previews to",
+			Value: "preview/",
+		},
+		&cli.IntSliceFlag{
+			Name:  "zoom",
+			Usage: "Write a preview at each of these multiples of the 70px canvas",
+			Value: []int{1, 4},
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
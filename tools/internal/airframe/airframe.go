@@ -0,0 +1,136 @@
+// Package airframe reads the airframe definition JSON files from the airframes directory.
+package airframe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// LatestVersion is the latest version of the airframe schema that can be read.
+const LatestVersion = 2
+
+type (
+	// Airframe represents the input JSON airframe schema defined at the root of this repo
+	Airframe struct {
+		Version int     `json:"version"`
+		ICAO    ICAO    `json:"icao"`
+		AliasOf *string `json:"aliasOf,omitempty"`
+		Render  Render  `json:"render"`
+		Art     Art     `json:"art"`
+		Notes   string  `json:"notes"`
+
+		// WingspanM and LengthM are the real-world dimensions in metres (schema v2), or 0 if unknown
+		WingspanM float64 `json:"wingspan_m,omitempty"`
+		LengthM   float64 `json:"length_m,omitempty"`
+
+		// Descriptive metadata (schema v2), see metadata.go
+		Manufacturer string   `json:"manufacturer,omitempty"`
+		Model        string   `json:"model,omitempty"`
+		CommonName   string   `json:"commonName,omitempty"`
+		Engines      *Engines `json:"engines,omitempty"`
+		Roles        []string `json:"roles,omitempty"`
+		AltText      string   `json:"altText,omitempty"`
+
+		// File is the path the airframe was read from
+		File string `json:"-"`
+	}
+
+	// ICAO represents the ICAO information from the input JSON airframe schema
+	ICAO struct {
+		Designator   string `json:"designator"`
+		TypeCode     string `json:"typeCode"`
+		WakeCategory string `json:"wakeCategory"`
+	}
+
+	// Render represents the sprite rendering information from the input JSON airframe schema.
+	// A Scale of 0 means it wasn't set, see ScaleCurve.
+	Render struct {
+		Scale    float64 `json:"scale"`
+		Anchor   Anchor  `json:"anchor"`
+		NoRotate bool    `json:"noRotate"`
+	}
+
+	// Anchor defines an x,y point from the top-left of the sprite, that shall be the
+	// anchor point of the sprite. Any rotation should be done about this point.
+	// The sprite should be drawn with this point on the pixel the sprite is indended to be drawn at.
+	Anchor struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+
+	// Art represents the sprite artwork from the input JSON airframe schema
+	Art struct {
+		Frames    []Frame `json:"frames"`
+		FrameTime int     `json:"frameTime"`
+	}
+
+	// Frame represents the sprite artwork from the input JSON airframe schema
+	Frame struct {
+		Src string `json:"src"`
+	}
+)
+
+// FromDir reads every airframe JSON file in dir.
+func FromDir(dir string) ([]*Airframe, error) {
+	return FromDirLogger(dir, log.Logger)
+}
+
+// FromDirLogger is FromDir, logging each file to logger rather than the global logger.
+func FromDirLogger(dir string, logger zerolog.Logger) ([]*Airframe, error) {
+	listing, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir: %w", err)
+	}
+
+	out := make([]*Airframe, 0, len(listing))
+
+	for _, entry := range listing {
+		if entry.IsDir() {
+			logger.Debug().Str("dir", entry.Name()).Msg("skipping dir")
+			continue
+		}
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			logger.Debug().Str("file", entry.Name()).Msg("skipping non-json file")
+			continue
+		}
+
+		af, err := FromFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to process file %s: %w", entry.Name(), err)
+		}
+
+		out = append(out, af)
+		logger.Info().
+			Str("icao", af.ICAO.Designator).
+			Msg("added airframe")
+	}
+
+	return out, nil
+}
+
+// FromFile reads a single airframe JSON file.
+func FromFile(filename string) (*Airframe, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	af := new(Airframe)
+	err = json.Unmarshal(b, af)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal airframe: %w", err)
+	}
+	if af.Version == 0 {
+		af.Version = 1
+	}
+	if err := af.check(); err != nil {
+		return nil, err
+	}
+	af.File = filename
+	return af, nil
+}
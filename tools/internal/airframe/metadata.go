@@ -0,0 +1,84 @@
+package airframe
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Engines describes an airframe's engines (schema v2).
+type Engines struct {
+	Count int    `json:"count"`
+	Type  string `json:"type,omitempty"` // one of EngineTypes, omitted if Count is 0
+}
+
+// EngineTypes maps each engine type to the letter that stands for it at the end of an ICAO type code.
+var EngineTypes = map[string]byte{
+	"piston":     'P',
+	"turboprop":  'T',
+	"turboshaft": 'T',
+	"jet":        'J',
+	"electric":   'E',
+	"rocket":     'R',
+}
+
+// Roles are the role tags an airframe can have (schema v2).
+var Roles = []string{"military", "cargo", "glider", "rotorcraft"}
+
+// rotorcraft are the ICAO type code letters for aircraft that fly on rotors: helicopters and gyrocopters.
+const rotorcraft = "HG"
+
+// Described returns whether the airframe has any descriptive metadata.
+func (af *Airframe) Described() bool {
+	return af.Manufacturer != "" || af.Model != "" || af.CommonName != "" || af.Engines != nil ||
+		len(af.Roles) > 0 || af.AltText != ""
+}
+
+// check checks that the airframe only uses fields its version has, and that its metadata is
+// consistent with its ICAO type code.
+func (af *Airframe) check() error {
+	if af.Version > LatestVersion {
+		return fmt.Errorf("unsupported version %d, the latest is %d", af.Version, LatestVersion)
+	}
+	if af.Version < 2 {
+		if af.WingspanM != 0 || af.LengthM != 0 {
+			return fmt.Errorf("wingspan_m and length_m need version 2")
+		}
+		if af.Described() {
+			return fmt.Errorf("manufacturer, model, commonName, engines, roles and altText need version 2")
+		}
+		return nil
+	}
+
+	typeCode := af.ICAO.TypeCode
+	if e := af.Engines; e != nil && len(typeCode) == 3 {
+		// the engine count is a digit, or C for coupled engines driving one propeller, which doesn't
+		// say how many there are, or - if unknown (eg: gliders)
+		if n := typeCode[1]; n >= '0' && n <= '9' && e.Count != int(n-'0') {
+			return fmt.Errorf("engines.count is %d, but typeCode %s says %c", e.Count, typeCode, n)
+		}
+		letter, ok := EngineTypes[e.Type]
+		switch {
+		case e.Count == 0 && e.Type != "":
+			return fmt.Errorf("engines.type is %q, but there are no engines", e.Type)
+		case e.Count > 0 && !ok:
+			return fmt.Errorf("unknown engines.type %q", e.Type)
+		case e.Count > 0 && typeCode[2] != '-' && letter != typeCode[2]:
+			return fmt.Errorf("engines.type is %q, but typeCode %s says %c", e.Type, typeCode, typeCode[2])
+		}
+	}
+
+	for _, r := range af.Roles {
+		if !slices.Contains(Roles, r) {
+			return fmt.Errorf("unknown role %q, expected one of %s", r, strings.Join(Roles, ", "))
+		}
+	}
+	// tiltrotors (T) fly on rotors and wings, so can be tagged either way
+	if len(typeCode) > 0 && typeCode[0] != 'T' && af.Roles != nil {
+		isRotorcraft := strings.IndexByte(rotorcraft, typeCode[0]) >= 0
+		if hasRole := slices.Contains(af.Roles, "rotorcraft"); hasRole != isRotorcraft {
+			return fmt.Errorf("roles should include rotorcraft if, and only if, typeCode %s starts with one of %s", typeCode, rotorcraft)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package airframe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// ScaleCurve derives render.scale from an airframe's real-world size, relative to a reference type.
+// An airframe's size is the larger of its wingspan and length, as that's what fills the 70×70 artwork.
+type ScaleCurve struct {
+	// Reference is the designator of the type the others are sized against
+	Reference string `json:"reference"`
+
+	// Curve is how scale grows with size relative to the reference:
+	//   - "linear": in proportion to it
+	//   - "log": by Strength for every doubling of it, ie: 1 + Strength × log2(size / reference size)
+	Curve    string  `json:"curve"`
+	Strength float64 `json:"strength,omitempty"`
+
+	// Min and Max clamp the derived scale
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// ScaleCurveFileName is the scale curve file's name. It sits beside the airframes directory.
+const ScaleCurveFileName = "render_scale.json"
+
+// ScaleCurveFile returns the scale curve file for the airframes in airframesPath: filename if it's
+// set, otherwise ScaleCurveFileName beside the airframes directory, wherever the tool is run from.
+func ScaleCurveFile(filename, airframesPath string) string {
+	if filename != "" {
+		return filename
+	}
+	return filepath.Join(filepath.Dir(filepath.Clean(airframesPath)), ScaleCurveFileName)
+}
+
+// ScaleCurveFromFile reads a scale curve file. If the file does not exist and missingOK is set, a
+// warning is logged, nil is returned, and no scales are derived.
+func ScaleCurveFromFile(filename string, missingOK bool) (*ScaleCurve, error) {
+	b, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) && missingOK {
+		log.Warn().Str("file", filename).Msg("no scale curve, so render.scale won't be derived from wingspan_m and length_m")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scale curve: %w", err)
+	}
+	c := new(ScaleCurve)
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal scale curve: %w", err)
+	}
+	switch {
+	case c.Reference == "":
+		return nil, fmt.Errorf("scale curve has no reference")
+	case c.Curve != "linear" && c.Curve != "log":
+		return nil, fmt.Errorf("unknown scale curve %q, expected linear or log", c.Curve)
+	case c.Curve == "log" && c.Strength <= 0:
+		return nil, fmt.Errorf("log scale curve needs a strength above 0")
+	case c.Min <= 0 || c.Max < c.Min:
+		return nil, fmt.Errorf("scale curve needs 0 < min <= max")
+	}
+	return c, nil
+}
+
+// Size returns the larger of the airframe's wingspan and length in metres, or 0 if neither is known.
+func (af *Airframe) Size() float64 {
+	return max(af.WingspanM, af.LengthM)
+}
+
+// Scale returns the scale for an airframe of the given size, where the reference is refSize.
+func (c *ScaleCurve) Scale(size, refSize float64) float64 {
+	var s float64
+	switch c.Curve {
+	case "log":
+		s = 1 + c.Strength*math.Log2(size/refSize)
+	default:
+		s = size / refSize
+	}
+	// round to 2 decimal places, like the scales in the airframe files
+	return math.Round(min(max(s, c.Min), c.Max)*100) / 100
+}
+
+// Derive sets render.scale for each canonical airframe that doesn't set it: from the curve if its
+// size is known, otherwise to the default of 1. Explicit scales are left as they are, including
+// the reference's, which the derived scales are multiplied by. Each derived scale is logged to logger.
+func (c *ScaleCurve) Derive(airframes []*Airframe, logger zerolog.Logger) error {
+	var ref *Airframe
+	for _, af := range airframes {
+		if af.ICAO.Designator == c.Reference && af.AliasOf == nil {
+			ref = af
+		}
+	}
+	refScale := 1.0
+	if ref != nil && ref.Render.Scale != 0 {
+		refScale = ref.Render.Scale
+	}
+
+	for _, af := range airframes {
+		if af.AliasOf != nil || af.Render.Scale != 0 {
+			continue
+		}
+		if af.Size() == 0 {
+			af.Render.Scale = 1
+			continue
+		}
+		if ref == nil {
+			return fmt.Errorf("scale curve reference %s is not a canonical airframe", c.Reference)
+		}
+		if ref.Size() == 0 {
+			return fmt.Errorf("scale curve reference %s has no wingspan_m or length_m", c.Reference)
+		}
+		af.Render.Scale = math.Round(refScale*c.Scale(af.Size(), ref.Size())*100) / 100
+		logger.Debug().
+			Str("airframe", af.ICAO.Designator).
+			Float64("size_m", af.Size()).
+			Float64("scale", af.Render.Scale).
+			Msg("derived scale")
+	}
+	return nil
+}
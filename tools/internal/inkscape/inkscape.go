@@ -0,0 +1,73 @@
+// Package inkscape rasterises SVGs by calling the Inkscape v1+ binary, so that every tool
+// renders silhouettes exactly as they appear in the spritesheet.
+package inkscape
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// ExportPNG converts src to a PNG at dst. If width is 0 the PNG is exported at the document size,
+// otherwise it is scaled to the given width in pixels.
+func ExportPNG(inkscapeBinary, src, dst string, width int) error {
+	args := []string{
+		src,
+		"--export-type=png",
+		"--export-overwrite",
+		"--export-filename=" + dst,
+	}
+	if width > 0 {
+		args = append(args, "--export-width="+strconv.Itoa(width))
+	}
+	cmd := exec.Command(inkscapeBinary, args...)
+
+	out, err := cmd.CombinedOutput()
+
+	// Inkscape sometimes logs useful info even on "success".
+	if err != nil {
+		return fmt.Errorf("inkscape failed: %w\noutput:\n%s", err, out)
+	}
+
+	// Don’t assume success: ensure the file exists and is non-zero.
+	st, statErr := os.Stat(dst)
+	if statErr != nil {
+		return fmt.Errorf("inkscape produced no output file: %v\noutput:\n%s", statErr, out)
+	}
+	if st.Size() == 0 {
+		return fmt.Errorf("inkscape produced empty output file (%s)\noutput:\n%s", dst, out)
+	}
+
+	return nil
+}
+
+// Render rasterises src via a temporary PNG and returns the decoded image. See ExportPNG for width.
+func Render(inkscapeBinary, src string, width int) (image.Image, error) {
+	tmpDir, err := os.MkdirTemp("", "svg2png-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpPngPath := filepath.Join(tmpDir, "out.png")
+
+	if err := ExportPNG(inkscapeBinary, src, tmpPngPath, width); err != nil {
+		return nil, fmt.Errorf("failed to convert SVG to PNG: %w", err)
+	}
+
+	f, err := os.Open(tmpPngPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open exported png: %w", err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	return img, nil
+}
@@ -0,0 +1,227 @@
+// Package jsonedit changes values in JSON documents in place, leaving the rest of the document,
+// including its formatting, exactly as it was.
+package jsonedit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Set replaces the value at path, a list of object keys from the root, with the JSON encoding of v.
+// If the last key isn't in its object it's added as the last member, spaced like the first.
+func Set(data []byte, v any, path ...string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	text, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	s := &scanner{data: data}
+	s.skipSpace()
+	obj, err := s.object()
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range path {
+		m, ok := obj.member(key)
+		last := i == len(path)-1
+		switch {
+		case ok && last:
+			return splice(data, m.ValueStart, m.ValueEnd, text), nil
+		case ok:
+			s.pos = m.ValueStart
+			if obj, err = s.object(); err != nil {
+				return nil, fmt.Errorf("%s: %w", strings.Join(path[:i+1], "."), err)
+			}
+		case last:
+			return obj.add(data, key, text), nil
+		default:
+			return nil, fmt.Errorf("%s not found", strings.Join(path[:i+1], "."))
+		}
+	}
+	panic("unreachable")
+}
+
+// object is where an object and each of its members are in the document.
+type object struct {
+	Start, End int // from the opening brace to just after the closing one
+	Members    []member
+}
+
+type member struct {
+	Key                  string
+	KeyStart             int
+	ValueStart, ValueEnd int
+}
+
+func (o *object) member(key string) (member, bool) {
+	for _, m := range o.Members {
+		if m.Key == key {
+			return m, true
+		}
+	}
+	return member{}, false
+}
+
+// add adds a member to the end of the object.
+func (o *object) add(data []byte, key string, value []byte) []byte {
+	k, _ := json.Marshal(key)
+	if len(o.Members) == 0 {
+		text := append(append(k, ": "...), value...)
+		return splice(data, o.Start+1, o.Start+1, text)
+	}
+	// space it like the first member, eg: on a new line with the same indent
+	space := data[o.Start+1 : o.Members[0].KeyStart]
+	text := append([]byte(","), space...)
+	text = append(append(append(text, k...), ": "...), value...)
+	end := o.Members[len(o.Members)-1].ValueEnd
+	return splice(data, end, end, text)
+}
+
+func splice(data []byte, start, end int, text []byte) []byte {
+	out := make([]byte, 0, len(data)-(end-start)+len(text))
+	out = append(out, data[:start]...)
+	out = append(out, text...)
+	return append(out, data[end:]...)
+}
+
+// scanner finds where values are in a JSON document.
+type scanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *scanner) errorf(format string, args ...any) error {
+	line := bytes.Count(s.data[:min(s.pos, len(s.data))], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.data) && strings.IndexByte(" \t\r\n", s.data[s.pos]) >= 0 {
+		s.pos++
+	}
+}
+
+func (s *scanner) expect(c byte) error {
+	s.skipSpace()
+	if s.pos >= len(s.data) || s.data[s.pos] != c {
+		return s.errorf("expected %q", c)
+	}
+	s.pos++
+	return nil
+}
+
+// object scans the object at the current position.
+func (s *scanner) object() (*object, error) {
+	o := &object{Start: s.pos}
+	if err := s.expect('{'); err != nil {
+		return nil, err
+	}
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == '}' {
+		s.pos++
+		o.End = s.pos
+		return o, nil
+	}
+	for {
+		s.skipSpace()
+		m := member{KeyStart: s.pos}
+		raw, err := s.str()
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &m.Key); err != nil {
+			return nil, s.errorf("invalid key: %v", err)
+		}
+		if err := s.expect(':'); err != nil {
+			return nil, err
+		}
+		s.skipSpace()
+		m.ValueStart = s.pos
+		if err := s.value(); err != nil {
+			return nil, err
+		}
+		m.ValueEnd = s.pos
+		o.Members = append(o.Members, m)
+
+		s.skipSpace()
+		if s.pos < len(s.data) && s.data[s.pos] == ',' {
+			s.pos++
+			continue
+		}
+		if err := s.expect('}'); err != nil {
+			return nil, err
+		}
+		o.End = s.pos
+		return o, nil
+	}
+}
+
+// value skips over the value at the current position.
+func (s *scanner) value() error {
+	if s.pos >= len(s.data) {
+		return s.errorf("unexpected end of JSON")
+	}
+	switch c := s.data[s.pos]; {
+	case c == '{':
+		_, err := s.object()
+		return err
+	case c == '[':
+		s.pos++
+		s.skipSpace()
+		if s.pos < len(s.data) && s.data[s.pos] == ']' {
+			s.pos++
+			return nil
+		}
+		for {
+			s.skipSpace()
+			if err := s.value(); err != nil {
+				return err
+			}
+			s.skipSpace()
+			if s.pos < len(s.data) && s.data[s.pos] == ',' {
+				s.pos++
+				continue
+			}
+			return s.expect(']')
+		}
+	case c == '"':
+		_, err := s.str()
+		return err
+	default:
+		// a number, true, false or null
+		start := s.pos
+		for s.pos < len(s.data) && strings.IndexByte(",}] \t\r\n", s.data[s.pos]) < 0 {
+			s.pos++
+		}
+		if start == s.pos {
+			return s.errorf("expected a value")
+		}
+		if !json.Valid(s.data[start:s.pos]) {
+			return s.errorf("invalid value %q", s.data[start:s.pos])
+		}
+		return nil
+	}
+}
+
+// str scans the string at the current position, and returns it as it's written.
+func (s *scanner) str() ([]byte, error) {
+	start := s.pos
+	if s.pos >= len(s.data) || s.data[s.pos] != '"' {
+		return nil, s.errorf("expected a string")
+	}
+	for s.pos++; s.pos < len(s.data); s.pos++ {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos++
+		case '"':
+			s.pos++
+			return s.data[start:s.pos], nil
+		}
+	}
+	return nil, s.errorf("unterminated string")
+}
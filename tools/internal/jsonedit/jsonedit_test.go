@@ -0,0 +1,154 @@
+package jsonedit
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		v    any
+		path []string
+		want string
+	}{
+		{
+			name: "top-level value",
+			data: `{"a": 1, "b": 2}`,
+			v:    3,
+			path: []string{"b"},
+			want: `{"a": 1, "b": 3}`,
+		},
+		{
+			name: "nested value, leaving the formatting alone",
+			data: "{\n  \"a\": {\n    \"b\":   1,\n    \"c\": [1, 2]\n  },\n  \"d\": true\n}\n",
+			v:    2.5,
+			path: []string{"a", "b"},
+			want: "{\n  \"a\": {\n    \"b\":   2.5,\n    \"c\": [1, 2]\n  },\n  \"d\": true\n}\n",
+		},
+		{
+			name: "value replaced by an object",
+			data: `{"a": {"b": [1, {"x": 2}]}}`,
+			v:    map[string]int{"x": 1},
+			path: []string{"a", "b"},
+			want: `{"a": {"b": {"x":1}}}`,
+		},
+		{
+			name: "same key in a different object",
+			data: `{"b": 1, "a": {"b": 2}}`,
+			v:    3,
+			path: []string{"a", "b"},
+			want: `{"b": 1, "a": {"b": 3}}`,
+		},
+		{
+			name: "missing member added to an empty object",
+			data: "{\n  \"a\": {}\n}\n",
+			v:    "x",
+			path: []string{"a", "b"},
+			want: "{\n  \"a\": {\"b\": \"x\"}\n}\n",
+		},
+		{
+			name: "missing member added to an empty root",
+			data: `{ }`,
+			v:    1,
+			path: []string{"a"},
+			want: `{"a": 1 }`,
+		},
+		{
+			name: "missing member added with the first member's indent",
+			data: "{\n  \"a\": {\n      \"b\": 1,\n      \"c\": 2\n  }\n}\n",
+			v:    3,
+			path: []string{"a", "d"},
+			want: "{\n  \"a\": {\n      \"b\": 1,\n      \"c\": 2,\n      \"d\": 3\n  }\n}\n",
+		},
+		{
+			name: "missing member added to a one-line object",
+			data: `{"a": 1}`,
+			v:    true,
+			path: []string{"b"},
+			want: `{"a": 1,"b": true}`,
+		},
+		{
+			name: "missing member added with tabs",
+			data: "{\n\t\"a\": 1\n}",
+			v:    nil,
+			path: []string{"b"},
+			want: "{\n\t\"a\": 1,\n\t\"b\": null\n}",
+		},
+		{
+			name: "escaped key",
+			data: `{"a\"b": 1, "cd": 2}`,
+			v:    5,
+			path: []string{"cd"},
+			want: `{"a\"b": 1, "cd": 5}`,
+		},
+		{
+			name: "key with a quote",
+			data: `{"a\"b": 1}`,
+			v:    5,
+			path: []string{`a"b`},
+			want: `{"a\"b": 5}`,
+		},
+		{
+			name: "added key is escaped",
+			data: `{"a": 1}`,
+			v:    2,
+			path: []string{`b"}`},
+			want: `{"a": 1,"b\"}": 2}`,
+		},
+		{
+			name: "strings containing braces and brackets",
+			data: `{"a": "}{", "b": ["]", "\"}"], "c": {"d": "{"}, "e": 1}`,
+			v:    2,
+			path: []string{"e"},
+			want: `{"a": "}{", "b": ["]", "\"}"], "c": {"d": "{"}, "e": 2}`,
+		},
+		{
+			name: "value with braces",
+			data: `{"a": 1}`,
+			v:    "{}",
+			path: []string{"a"},
+			want: `{"a": "{}"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Set([]byte(tt.data), tt.v, tt.path...)
+			if err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Set(%q, %v, %q)\n got %q\nwant %q", tt.data, tt.v, tt.path, got, tt.want)
+			}
+			if !json.Valid(got) {
+				t.Errorf("Set(%q, %v, %q) = %q, which isn't valid JSON", tt.data, tt.v, tt.path, got)
+			}
+		})
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		path []string
+	}{
+		{"empty path", `{"a": 1}`, nil},
+		{"missing intermediate key", `{"a": {"b": 1}}`, []string{"x", "b"}},
+		{"missing deeper intermediate key", `{"a": {"b": 1}}`, []string{"a", "x", "c"}},
+		{"intermediate value isn't an object", `{"a": [1]}`, []string{"a", "b"}},
+		{"root isn't an object", `[1]`, []string{"a"}},
+		{"truncated", `{"a": {"b": 1`, []string{"a", "b"}},
+		{"unterminated string", `{"a": "x}`, []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Set([]byte(tt.data), 1, tt.path...); err == nil {
+				t.Errorf("Set(%q, 1, %q) = %q, want an error", tt.data, tt.path, got)
+			}
+		})
+	}
+}
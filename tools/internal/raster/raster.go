@@ -0,0 +1,310 @@
+// Package raster holds the bitmap operations shared by the tools that compare and trace
+// rendered silhouettes.
+package raster
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+)
+
+// Mask is a bitmap of which pixels are on.
+type Mask struct {
+	W, H int
+	On   []bool
+}
+
+// NewMask returns a mask with every pixel off.
+func NewMask(w, h int) *Mask {
+	return &Mask{W: w, H: h, On: make([]bool, w*h)}
+}
+
+// MaskFrom returns the coverage of a rendered image: which pixels are at least half opaque.
+func MaskFrom(img image.Image) *Mask {
+	b := img.Bounds()
+	m := NewMask(b.Dx(), b.Dy())
+	for y := 0; y < m.H; y++ {
+		for x := 0; x < m.W; x++ {
+			_, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			m.On[y*m.W+x] = a >= 0x8000
+		}
+	}
+	return m
+}
+
+// At returns whether a pixel is on. Pixels outside the mask are off.
+func (m *Mask) At(x, y int) bool {
+	if x < 0 || y < 0 || x >= m.W || y >= m.H {
+		return false
+	}
+	return m.On[y*m.W+x]
+}
+
+// Count returns the number of pixels that are on.
+func (m *Mask) Count() int {
+	n := 0
+	for _, on := range m.On {
+		if on {
+			n++
+		}
+	}
+	return n
+}
+
+// BBox returns the bounds of the pixels that are on.
+func (m *Mask) BBox() image.Rectangle {
+	r := image.Rectangle{}
+	for y := 0; y < m.H; y++ {
+		for x := 0; x < m.W; x++ {
+			if m.On[y*m.W+x] {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+// Or returns the pixels on in either m or o.
+func (m *Mask) Or(o *Mask) *Mask {
+	out := NewMask(m.W, m.H)
+	for i := range m.On {
+		out.On[i] = m.On[i] || o.On[i]
+	}
+	return out
+}
+
+// And returns the pixels on in both m and o.
+func (m *Mask) And(o *Mask) *Mask {
+	out := NewMask(m.W, m.H)
+	for i := range m.On {
+		out.On[i] = m.On[i] && o.On[i]
+	}
+	return out
+}
+
+// AndNot returns the pixels on in m but not in o.
+func (m *Mask) AndNot(o *Mask) *Mask {
+	out := NewMask(m.W, m.H)
+	for i := range m.On {
+		out.On[i] = m.On[i] && !o.On[i]
+	}
+	return out
+}
+
+// Open removes everything narrower than a square of the given radius: an erosion followed by a dilation.
+func (m *Mask) Open(radius int) *Mask {
+	return m.Spread(radius, false).Spread(radius, true)
+}
+
+// Close fills in gaps narrower than a square of the given radius: a dilation followed by an erosion.
+func (m *Mask) Close(radius int) *Mask {
+	return m.Spread(radius, true).Spread(radius, false)
+}
+
+// Spread dilates (on == true) or erodes (on == false) m by a square of the given radius.
+// Each pixel becomes on (dilate) or off (erode) if any pixel within the radius is.
+func (m *Mask) Spread(radius int, on bool) *Mask {
+	pass := func(src *Mask, dx, dy int) *Mask {
+		out := NewMask(src.W, src.H)
+		for y := 0; y < src.H; y++ {
+			for x := 0; x < src.W; x++ {
+				v := !on
+				for k := -radius; k <= radius; k++ {
+					if src.At(x+k*dx, y+k*dy) == on {
+						v = on
+						break
+					}
+				}
+				out.On[y*src.W+x] = v
+			}
+		}
+		return out
+	}
+	// a square is separable into a horizontal then a vertical pass
+	return pass(pass(m, 1, 0), 0, 1)
+}
+
+// Diff counts the pixels that differ between m and o, with o offset by (dx,dy).
+func (m *Mask) Diff(o *Mask, dx, dy int) int {
+	n := 0
+	for y := 0; y < m.H; y++ {
+		for x := 0; x < m.W; x++ {
+			if m.On[y*m.W+x] != o.At(x+dx, y+dy) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Edges returns the pixels that are on, but next to a pixel that's off (or the edge of the mask).
+func (m *Mask) Edges() *Mask {
+	out := NewMask(m.W, m.H)
+	for y := 0; y < m.H; y++ {
+		for x := 0; x < m.W; x++ {
+			out.On[y*m.W+x] = m.On[y*m.W+x] && !(m.At(x-1, y) && m.At(x+1, y) && m.At(x, y-1) && m.At(x, y+1))
+		}
+	}
+	return out
+}
+
+// Distances returns the distance from each pixel to the nearest pixel that's on, indexed like On.
+// If no pixels are on, every distance is +Inf.
+func (m *Mask) Distances() []float64 {
+	// squared distances, computed exactly with separate passes over columns then rows, using
+	// the method from Felzenszwalb and Huttenlocher's "Distance Transforms of Sampled Functions"
+	inf := math.Inf(1)
+	d := make([]float64, len(m.On))
+	for i, on := range m.On {
+		if !on {
+			d[i] = inf
+		}
+	}
+	col := make([]float64, m.H)
+	for x := 0; x < m.W; x++ {
+		for y := range col {
+			col[y] = d[y*m.W+x]
+		}
+		col = distances1D(col)
+		for y, v := range col {
+			d[y*m.W+x] = v
+		}
+	}
+	for y := 0; y < m.H; y++ {
+		copy(d[y*m.W:], distances1D(d[y*m.W:(y+1)*m.W]))
+	}
+	for i := range d {
+		d[i] = math.Sqrt(d[i])
+	}
+	return d
+}
+
+// distances1D returns the lower envelope of the parabolas (x-q)² + f(q): the squared distance
+// along a line, given the squared distances already found in the other direction.
+func distances1D(f []float64) []float64 {
+	n := len(f)
+	out := make([]float64, n)
+	v := make([]int, 0, n)       // positions of the parabolas in the envelope
+	z := make([]float64, 0, n+1) // where each takes over from the last
+
+	intersect := func(q, p int) float64 {
+		return ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+	}
+	for q := 0; q < n; q++ {
+		if math.IsInf(f[q], 1) {
+			continue
+		}
+		for len(v) > 0 {
+			if s := intersect(q, v[len(v)-1]); s > z[len(z)-1] {
+				z = append(z, s)
+				break
+			}
+			v, z = v[:len(v)-1], z[:len(z)-1]
+		}
+		if len(v) == 0 {
+			z = append(z[:0], math.Inf(-1))
+		}
+		v = append(v, q)
+	}
+	if len(v) == 0 {
+		for i := range out {
+			out[i] = math.Inf(1)
+		}
+		return out
+	}
+
+	k := 0
+	for q := 0; q < n; q++ {
+		for k+1 < len(v) && z[k+1] < float64(q) {
+			k++
+		}
+		dq := float64(q - v[k])
+		out[q] = dq*dq + f[v[k]]
+	}
+	return out
+}
+
+// FillHoles turns on every pixel that can't be reached from the edge of the mask without crossing
+// a pixel that's on, so outlines become solid shapes.
+func (m *Mask) FillHoles() *Mask {
+	outside := m.flood(func(i int) bool { return !m.On[i] }, m.edge())
+	out := NewMask(m.W, m.H)
+	for i := range out.On {
+		out.On[i] = !outside[i]
+	}
+	return out
+}
+
+// Nearest returns the 4-connected group of pixels that are on with the pixel closest to p.
+func (m *Mask) Nearest(p image.Point) *Mask {
+	best, bestD := -1, 0
+	for i, on := range m.On {
+		if !on {
+			continue
+		}
+		dx, dy := i%m.W-p.X, i/m.W-p.Y
+		if d := dx*dx + dy*dy; best < 0 || d < bestD {
+			best, bestD = i, d
+		}
+	}
+	out := NewMask(m.W, m.H)
+	if best >= 0 {
+		out.On = m.flood(func(j int) bool { return m.On[j] }, []int{best})
+	}
+	return out
+}
+
+// edge returns the indexes of the pixels around the edge of the mask.
+func (m *Mask) edge() []int {
+	var out []int
+	for x := 0; x < m.W; x++ {
+		out = append(out, x, (m.H-1)*m.W+x)
+	}
+	for y := 1; y < m.H-1; y++ {
+		out = append(out, y*m.W, y*m.W+m.W-1)
+	}
+	return out
+}
+
+// flood returns the pixels 4-connected to the seeds through pixels that pass the test.
+func (m *Mask) flood(test func(i int) bool, seeds []int) []bool {
+	in := make([]bool, len(m.On))
+	var stack []int
+	for _, i := range seeds {
+		if !in[i] && test(i) {
+			in[i] = true
+			stack = append(stack, i)
+		}
+	}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x, y := i%m.W, i/m.W
+		for _, n := range [][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+			if n[0] < 0 || n[1] < 0 || n[0] >= m.W || n[1] >= m.H {
+				continue
+			}
+			j := n[1]*m.W + n[0]
+			if !in[j] && test(j) {
+				in[j] = true
+				stack = append(stack, j)
+			}
+		}
+	}
+	return in
+}
+
+// WritePNG writes an image to a PNG file.
+func WritePNG(filename string, img image.Image) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filename, err)
+	}
+	return nil
+}
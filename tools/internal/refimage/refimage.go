@@ -0,0 +1,430 @@
+// Package refimage finds the reference artwork in a silhouette's hidden "Reference Artwork" layer,
+// and places it on the silhouette's canvas, so it can be compared with (or traced into) the outline.
+package refimage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // decoders for the types of reference image
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/raster"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/plane-watch/pw-silhouettes/internal/svgpath"
+)
+
+const (
+	inkscapeNS = "http://www.inkscape.org/namespaces/inkscape"
+	xlinkNS    = "http://www.w3.org/1999/xlink"
+
+	referenceLayerLabel = "Reference Artwork"
+)
+
+// ErrNoReference is returned when a silhouette has no reference image that can be used.
+var ErrNoReference = errors.New("no reference image")
+
+// ErrUnsupportedReference is returned when a silhouette's reference image is in a format that can't
+// be read, such as SVG.
+var ErrUnsupportedReference = errors.New("unsupported reference image")
+
+// Reference is a reference image, placed on a silhouette's canvas.
+type Reference struct {
+	Image        image.Image
+	ToCanvas     svgpath.Matrix // from image pixels to canvas px
+	Line         int            // of the <image> element
+	Canvas       image.Point    // size of the canvas in px
+	RootToCanvas svgpath.Matrix // from the root's user units to canvas px
+}
+
+// candidate is an <image> found in the reference layer.
+type candidate struct {
+	line   int
+	href   string
+	box    [4]float64     // x, y, width and height
+	aspect string         // preserveAspectRatio
+	toRoot svgpath.Matrix // from the <image> element's user units to root user units
+}
+
+// Load finds the reference image of an SVG file. If the reference layer has several images, the one
+// covering most of the canvas is used.
+func Load(svgPath string) (*Reference, error) {
+	data, err := os.ReadFile(svgPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read svg: %w", err)
+	}
+
+	rootToPx, canvas, candidates, err := scan(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *Reference
+	var bestArea float64
+	for _, c := range candidates {
+		img, err := decode(svgPath, c.href)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", c.line, err)
+		}
+		ref := &Reference{
+			Image:        img,
+			ToCanvas:     rootToPx.Mul(c.toRoot).Mul(fit(img.Bounds(), c.box, c.aspect)),
+			Line:         c.line,
+			Canvas:       canvas,
+			RootToCanvas: rootToPx,
+		}
+		if area := ref.canvasOverlap(); best == nil || area > bestArea {
+			best, bestArea = ref, area
+		}
+	}
+	if best == nil {
+		return nil, ErrNoReference
+	}
+	return best, nil
+}
+
+// canvasOverlap returns the area of the canvas covered by the image's bounding box.
+func (r *Reference) canvasOverlap() float64 {
+	b := r.Image.Bounds()
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range []image.Point{b.Min, {b.Max.X, b.Min.Y}, b.Max, {b.Min.X, b.Max.Y}} {
+		q := r.ToCanvas.Apply(svgpath.Point{X: float64(p.X), Y: float64(p.Y)})
+		minX, minY = min(minX, q.X), min(minY, q.Y)
+		maxX, maxY = max(maxX, q.X), max(maxY, q.Y)
+	}
+	w := min(maxX, float64(r.Canvas.X)) - max(minX, 0)
+	h := min(maxY, float64(r.Canvas.Y)) - max(minY, 0)
+	return max(w, 0) * max(h, 0)
+}
+
+// scan walks the SVG for <image> elements in the reference layer, returning the transform from root
+// user units to canvas px, the size of the canvas in px, and the images.
+func scan(data []byte) (svgpath.Matrix, image.Point, []candidate, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	type frame struct {
+		ctm       svgpath.Matrix
+		reference bool
+	}
+	stack := []frame{{ctm: svgpath.Identity}}
+	rootToPx := svgpath.Identity
+	var canvas image.Point
+	var out []candidate
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rootToPx, canvas, nil, fmt.Errorf("xml parse error: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			line, _ := dec.InputPos()
+			parent := stack[len(stack)-1]
+			f := frame{ctm: parent.ctm, reference: parent.reference || isReferenceLayer(t)}
+
+			attrs := map[string]string{}
+			var href string
+			for _, a := range t.Attr {
+				if a.Name.Space == "" {
+					attrs[a.Name.Local] = a.Value
+				}
+				if a.Name.Local == "href" && (a.Name.Space == "" || a.Name.Space == xlinkNS) {
+					href = a.Value
+				}
+			}
+
+			if len(stack) == 1 {
+				var err error
+				if rootToPx, canvas, err = rootTransform(attrs); err != nil {
+					return rootToPx, canvas, nil, fmt.Errorf("line %d: %w", line, err)
+				}
+			} else if v, ok := attrs["transform"]; ok {
+				m, err := svgpath.ParseTransform(v)
+				if err != nil {
+					return rootToPx, canvas, nil, fmt.Errorf("line %d: <%s> invalid transform: %w", line, t.Name.Local, err)
+				}
+				f.ctm = f.ctm.Mul(m)
+			}
+
+			if t.Name.Local == "image" && f.reference && href != "" {
+				box, err := imageBox(attrs)
+				if err != nil {
+					return rootToPx, canvas, nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				out = append(out, candidate{line: line, href: href, box: box, aspect: attrs["preserveAspectRatio"], toRoot: f.ctm})
+			}
+			stack = append(stack, f)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return rootToPx, canvas, out, nil
+}
+
+func isReferenceLayer(t xml.StartElement) bool {
+	var layer, label bool
+	for _, a := range t.Attr {
+		if a.Name.Space != inkscapeNS {
+			continue
+		}
+		switch a.Name.Local {
+		case "groupmode":
+			layer = a.Value == "layer"
+		case "label":
+			label = strings.TrimSpace(a.Value) == referenceLayerLabel
+		}
+	}
+	return layer && label
+}
+
+// rootTransform returns the transform from the root's user units to px, and the canvas size in px.
+func rootTransform(attrs map[string]string) (svgpath.Matrix, image.Point, error) {
+	w, errW := parseLength(attrs["width"])
+	h, errH := parseLength(attrs["height"])
+	if errW != nil || errH != nil {
+		return svgpath.Matrix{}, image.Point{}, fmt.Errorf("root <svg> must have a width and height in px")
+	}
+	canvas := image.Pt(int(math.Round(w)), int(math.Round(h)))
+
+	f := strings.Fields(strings.ReplaceAll(attrs["viewBox"], ",", " "))
+	if len(f) != 4 {
+		return svgpath.Identity, canvas, nil
+	}
+	var vb [4]float64
+	for i := range vb {
+		v, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return svgpath.Matrix{}, image.Point{}, fmt.Errorf("invalid viewBox %q", attrs["viewBox"])
+		}
+		vb[i] = v
+	}
+	if vb[2] <= 0 || vb[3] <= 0 {
+		return svgpath.Matrix{}, image.Point{}, fmt.Errorf("invalid viewBox %q", attrs["viewBox"])
+	}
+	sx, sy := w/vb[2], h/vb[3]
+	return svgpath.Matrix{A: sx, D: sy, E: -vb[0] * sx, F: -vb[1] * sy}, canvas, nil
+}
+
+// imageBox returns the x, y, width and height of an <image>.
+func imageBox(attrs map[string]string) ([4]float64, error) {
+	var v [4]float64
+	for i, name := range []string{"x", "y", "width", "height"} {
+		s, ok := attrs[name]
+		if !ok && (name == "x" || name == "y") {
+			continue
+		}
+		n, err := parseLength(s)
+		if err != nil {
+			return v, fmt.Errorf("<image> invalid %s %q", name, s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// fit returns the transform from image pixels to the user units of its <image> element, which draws
+// it in box according to preserveAspectRatio. With "slice", the parts outside the box aren't clipped.
+func fit(b image.Rectangle, box [4]float64, aspect string) svgpath.Matrix {
+	iw, ih := float64(b.Dx()), float64(b.Dy())
+	sx, sy := box[2]/iw, box[3]/ih
+
+	fields := strings.Fields(aspect)
+	if len(fields) > 0 && fields[0] == "defer" {
+		fields = fields[1:]
+	}
+	align := "xMidYMid"
+	if len(fields) > 0 {
+		align = fields[0]
+	}
+	var ox, oy float64
+	if align != "none" {
+		s := min(sx, sy)
+		if len(fields) > 1 && fields[1] == "slice" {
+			s = max(sx, sy)
+		}
+		sx, sy = s, s
+		pos := func(a string, spare float64) float64 {
+			switch a {
+			case "Mid":
+				return spare / 2
+			case "Max":
+				return spare
+			}
+			return 0
+		}
+		if len(align) == 8 {
+			ox = pos(align[1:4], box[2]-iw*s)
+			oy = pos(align[5:8], box[3]-ih*s)
+		}
+	}
+	return svgpath.Matrix{A: sx, D: sy, E: box[0] + ox - float64(b.Min.X)*sx, F: box[1] + oy - float64(b.Min.Y)*sy}
+}
+
+func parseLength(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+}
+
+// decode reads a reference image, either embedded as a data: URL or linked into the reference directory.
+func decode(svgPath, href string) (image.Image, error) {
+	var data []byte
+	if strings.HasPrefix(strings.TrimSpace(href), "data:") {
+		mimeType, b, err := svgdoc.DecodeDataURL(href)
+		if err != nil {
+			return nil, err
+		}
+		if mimeType == "image/svg+xml" {
+			return nil, fmt.Errorf("%w: it's an SVG", ErrUnsupportedReference)
+		}
+		data = b
+	} else {
+		rel, ok := svgdoc.ReferenceLink(href)
+		if !ok {
+			return nil, fmt.Errorf("reference image %q is not embedded or linked into %s/", href, svgdoc.ReferenceDir)
+		}
+		var err error
+		if data, err = os.ReadFile(filepath.Join(filepath.Dir(svgPath), rel)); err != nil {
+			return nil, fmt.Errorf("failed to read reference image: %w", err)
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode reference image: %w", err)
+	}
+	return img, nil
+}
+
+// Resample draws the reference image onto a grid covering the canvas at zoom pixels per canvas px,
+// composited onto white. Each pixel is the average of the image pixels it covers, so thin lines
+// fade rather than disappear.
+func (r *Reference) Resample(zoom int) *image.NRGBA {
+	out := image.NewNRGBA(image.Rect(0, 0, r.Canvas.X*zoom, r.Canvas.Y*zoom))
+	inv, ok := r.ToCanvas.Invert()
+	if !ok {
+		return out
+	}
+
+	// enough samples per pixel to see every image pixel under it
+	n := min(max(int(math.Ceil(inv.Scale()/float64(zoom))), 1), 8)
+
+	b := r.Image.Bounds()
+	for y := 0; y < out.Rect.Dy(); y++ {
+		for x := 0; x < out.Rect.Dx(); x++ {
+			var sum [3]float64
+			for sy := 0; sy < n; sy++ {
+				for sx := 0; sx < n; sx++ {
+					p := inv.Apply(svgpath.Point{
+						X: (float64(x) + (float64(sx)+0.5)/float64(n)) / float64(zoom),
+						Y: (float64(y) + (float64(sy)+0.5)/float64(n)) / float64(zoom),
+					})
+					c := bilinear(r.Image, b, p.X-0.5, p.Y-0.5)
+					sum[0] += float64(c.R)
+					sum[1] += float64(c.G)
+					sum[2] += float64(c.B)
+				}
+			}
+			k := float64(n * n)
+			out.SetNRGBA(x, y, color.NRGBA{R: uint8(sum[0]/k + 0.5), G: uint8(sum[1]/k + 0.5), B: uint8(sum[2]/k + 0.5), A: 0xff})
+		}
+	}
+	return out
+}
+
+// bilinear samples an image at a (possibly fractional) position, where whole numbers are pixel centres.
+// Outside the image, and where it's transparent, is white.
+func bilinear(img image.Image, b image.Rectangle, fx, fy float64) color.NRGBA {
+	x0, y0 := int(math.Floor(fx)), int(math.Floor(fy))
+	tx, ty := fx-float64(x0), fy-float64(y0)
+
+	var sum [3]float64
+	for _, s := range [4]struct {
+		x, y int
+		w    float64
+	}{
+		{x0, y0, (1 - tx) * (1 - ty)},
+		{x0 + 1, y0, tx * (1 - ty)},
+		{x0, y0 + 1, (1 - tx) * ty},
+		{x0 + 1, y0 + 1, tx * ty},
+	} {
+		px, py := b.Min.X+s.x, b.Min.Y+s.y
+		rgb := [3]float64{0xffff, 0xffff, 0xffff}
+		if image.Pt(px, py).In(b) {
+			r, g, bl, a := img.At(px, py).RGBA()
+			// premultiplied, so composite onto white by adding what the alpha leaves uncovered
+			rgb = [3]float64{float64(r + 0xffff - a), float64(g + 0xffff - a), float64(bl + 0xffff - a)}
+		}
+		for i := range sum {
+			sum[i] += s.w * rgb[i]
+		}
+	}
+	return color.NRGBA{R: uint8(sum[0] / 257), G: uint8(sum[1] / 257), B: uint8(sum[2] / 257), A: 0xff}
+}
+
+// Ink returns the pixels of a resampled reference that differ from its background (the most
+// common colour around its edge) by more than threshold, from 0 to 1 in any channel.
+func Ink(img *image.NRGBA, threshold float64) *raster.Mask {
+	b := img.Rect
+	counts := map[color.NRGBA]int{}
+	var bg color.NRGBA
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for _, y := range []int{b.Min.Y, b.Max.Y - 1} {
+			c := img.NRGBAAt(x, y)
+			// quantise a little, so noise in photos and JPEG artefacts still agree
+			c = color.NRGBA{R: c.R &^ 7, G: c.G &^ 7, B: c.B &^ 7, A: 0xff}
+			counts[c]++
+			if counts[c] > counts[bg] {
+				bg = c
+			}
+		}
+	}
+
+	m := raster.NewMask(b.Dx(), b.Dy())
+	limit := threshold * 255
+	for y := 0; y < m.H; y++ {
+		for x := 0; x < m.W; x++ {
+			c := img.NRGBAAt(b.Min.X+x, b.Min.Y+y)
+			d := max(absDiff(c.R, bg.R), absDiff(c.G, bg.G), absDiff(c.B, bg.B))
+			m.On[y*m.W+x] = float64(d) > limit
+		}
+	}
+	return m
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// Silhouette returns the solid shape of the aircraft in a resampled reference: the thing drawn
+// nearest the middle of the canvas, where the top view is placed, with gaps up to closeRadius
+// pixels closed and filled in. Holes are filled before shrinking back from the closing's dilation,
+// so a break in an outline doesn't leave the inside empty even when the ends either side of it
+// don't line up. Parts up to openRadius pixels across, such as dimension lines touching the
+// aircraft, are then removed.
+func Silhouette(img *image.NRGBA, threshold float64, closeRadius, openRadius int) *raster.Mask {
+	centre := image.Pt(img.Rect.Dx()/2, img.Rect.Dy()/2)
+	m := Ink(img, threshold).Spread(closeRadius, true).Nearest(centre).FillHoles().Spread(closeRadius, false)
+	if openRadius > 0 {
+		m = m.Open(openRadius)
+	}
+	return m.Nearest(centre)
+}
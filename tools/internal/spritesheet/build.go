@@ -0,0 +1,272 @@
+package spritesheet
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"time"
+
+	"github.com/plane-watch/pw-silhouettes/internal/airframe"
+	"github.com/plane-watch/pw-silhouettes/internal/inkscape"
+	"github.com/plane-watch/pw-silhouettes/internal/raster"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed original_sprites.png
+var originalSpriteData []byte
+
+const (
+	SpriteWidth  = 72
+	SpriteHeight = 72
+)
+
+// Builder builds spritesheets. It keeps each SVG it renders, so later builds only render the SVGs
+// that have changed since.
+type Builder struct {
+	InkscapeBinary string
+
+	// ScaleCurve, if set, derives the scale of airframes that don't set one from their size
+	ScaleCurve *airframe.ScaleCurve
+
+	// Logger, if set, is logged to instead of the global logger
+	Logger *zerolog.Logger
+
+	renders map[string]render
+}
+
+// render is an SVG as rendered by Inkscape
+type render struct {
+	ModTime time.Time
+	Size    int64
+	Image   image.Image
+}
+
+// logger returns the logger to log to.
+func (b *Builder) logger() *zerolog.Logger {
+	if b.Logger != nil {
+		return b.Logger
+	}
+	return &log.Logger
+}
+
+func buildSpriteMap(logger *zerolog.Logger, airframes []*airframe.Airframe, idOffset int) map[string]int {
+	spriteSet := make(map[string]int)
+	n := 0 + idOffset
+	for _, af := range airframes {
+		for _, frame := range af.Art.Frames {
+			if _, ok := spriteSet[frame.Src]; ok {
+				logger.Info().
+					Str("airframe", af.ICAO.Designator).
+					Str("src", frame.Src).
+					Msg("skipping duplicate")
+				continue
+			}
+			logger.Info().
+				Str("airframe", af.ICAO.Designator).
+				Str("src", frame.Src).
+				Int("sprite_id", n).
+				Msg("adding sprite")
+			spriteSet[frame.Src] = n
+			n++
+		}
+	}
+	return spriteSet
+}
+
+// Build draws the airframes' artwork onto the original spritesheet, and describes it. pngPath is
+// where the spritesheet PNG will be written, for the JSON's metadata.
+func (b *Builder) Build(airframes []*airframe.Airframe, pngPath string) (*Output, *image.NRGBA, error) {
+
+	if b.ScaleCurve != nil {
+		if err := b.ScaleCurve.Derive(airframes, *b.logger()); err != nil {
+			return nil, nil, fmt.Errorf("failed to derive scales: %w", err)
+		}
+	}
+
+	// open existing spritesheet
+	img, err := png.Decode(bytes.NewBuffer(originalSpriteData))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode fallback spritesheet: %w", err)
+	}
+	bounds := img.Bounds()
+
+	// We will use the original spritesheet width.
+	// We will increase the height based on number of new sprites.
+	width, height := bounds.Max.X, bounds.Max.Y
+	spritesPerRow := width / SpriteWidth
+	rows := height / SpriteHeight
+	existingMaxSpriteID := (spritesPerRow * rows) - 1 // -1 as zero indexed
+
+	// generate unique set of sprites (as some airframes reference the same sprites)
+	newSprites := buildSpriteMap(b.logger(), airframes, existingMaxSpriteID+1)
+
+	// Work out how much additional height we should add
+	numNewSprites := len(newSprites)
+	numNewRows := int(math.Ceil(float64(numNewSprites) / float64(spritesPerRow)))
+
+	// Work out new img height
+	extraHeight := numNewRows * SpriteHeight
+	newHeight := height + extraHeight
+
+	// Create new image
+	newImg := image.NewNRGBA(image.Rect(0, 0, width, newHeight))
+
+	// Copy existing spritesheet into new image
+	drawImageOnto(img, newImg, 0, 0)
+
+	for svgFile, spriteNum := range newSprites {
+		b.logger().Info().
+			Int("sprite_id", spriteNum).
+			Str("svg_file", svgFile).
+			Msg("adding sprite to spritesheet")
+
+		offX, offY, err := TopLeft(spriteNum, width, SpriteWidth, SpriteHeight, 0, 0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get top left: %w", err)
+		}
+		err = b.drawSVGOnto(svgFile, newImg, offX+1, offY+1)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to draw SVG onto new spritesheet: %w", err)
+		}
+	}
+
+	// Prepare output JSON
+	out := new(Output)
+	out.Version = 1
+	out.Metadata = Metadata{
+		PNG:          pngPath,
+		SpriteWidth:  SpriteWidth,
+		SpriteHeight: SpriteHeight,
+	}
+	out.AirframeToSprite = make(map[string]string, len(airframes))
+	out.Sprites = make(map[string]Sprite, len(newSprites))
+	for _, af := range airframes {
+		// aliases are described separately, as they're different types
+		if af.Described() {
+			if out.Airframes == nil {
+				out.Airframes = make(map[string]Airframe)
+			}
+			out.Airframes[af.ICAO.Designator] = Airframe{
+				Manufacturer: af.Manufacturer,
+				Model:        af.Model,
+				CommonName:   af.CommonName,
+				Engines:      af.Engines,
+				Roles:        af.Roles,
+				AltText:      af.AltText,
+			}
+		}
+
+		if af.AliasOf != nil {
+			out.AirframeToSprite[af.ICAO.Designator] = *af.AliasOf
+			continue
+		}
+
+		// create the sprite
+		scale := af.Render.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		s := Sprite{
+			IDs:      make([]int, 0, 4),
+			Scale:    scale,
+			Anchor:   af.Render.Anchor,
+			NoRotate: af.Render.NoRotate,
+		}
+		if af.Art.FrameTime != 0 {
+			s.FrameTime = &af.Art.FrameTime
+		}
+		if af.Size() > 0 {
+			if s.MetresPerPixel, err = b.metresPerPixel(af); err != nil {
+				return nil, nil, fmt.Errorf("failed to measure %s: %w", af.ICAO.Designator, err)
+			}
+		}
+		// add sprite IDs
+		for _, src := range af.Art.Frames {
+			s.IDs = append(s.IDs, newSprites[src.Src])
+		}
+		// add sprite to output
+		out.Sprites[af.ICAO.Designator] = s
+		// add airframe to output
+		out.AirframeToSprite[af.ICAO.Designator] = af.ICAO.Designator
+	}
+
+	return out, newImg, nil
+}
+
+// metresPerPixel measures the extent of the airframe's first frame, and divides its real-world
+// dimensions by it: its wingspan by the width, and its length by the height. If both are known,
+// it's the mean of the two.
+func (b *Builder) metresPerPixel(af *airframe.Airframe) (float64, error) {
+	img, err := b.render(af.Art.Frames[0].Src)
+	if err != nil {
+		return 0, err
+	}
+	box := raster.MaskFrom(img).BBox()
+	if box.Empty() {
+		return 0, fmt.Errorf("%s has nothing visible", af.Art.Frames[0].Src)
+	}
+
+	var estimates []float64
+	if af.WingspanM > 0 {
+		estimates = append(estimates, af.WingspanM/float64(box.Dx()))
+	}
+	if af.LengthM > 0 {
+		estimates = append(estimates, af.LengthM/float64(box.Dy()))
+	}
+	mpp := estimates[0]
+	if len(estimates) == 2 {
+		mpp = (estimates[0] + estimates[1]) / 2
+		if d := math.Abs(estimates[0]-estimates[1]) / mpp; d > 0.1 {
+			b.logger().Warn().
+				Str("airframe", af.ICAO.Designator).
+				Float64("wingspan_mpp", estimates[0]).
+				Float64("length_mpp", estimates[1]).
+				Msgf("artwork's proportions differ from wingspan_m and length_m by %.0f%%", 100*d)
+		}
+	}
+	return math.Round(mpp*10000) / 10000, nil
+}
+
+func drawImageOnto(src, dst image.Image, offsetX, offsetY int) {
+	for y := 0; y < src.(*image.NRGBA).Bounds().Dy(); y++ {
+		for x := 0; x < src.(*image.NRGBA).Bounds().Dx(); x++ {
+			dst.(*image.NRGBA).Set(x+offsetX, y+offsetY, src.At(x, y))
+		}
+	}
+}
+
+func (b *Builder) drawSVGOnto(src string, dst image.Image, offsetX, offsetY int) error {
+	pngImage, err := b.render(src)
+	if err != nil {
+		return err
+	}
+
+	drawImageOnto(pngImage, dst, offsetX, offsetY)
+	return nil
+}
+
+// render renders src with Inkscape, unless it hasn't changed since it was last rendered.
+func (b *Builder) render(src string) (image.Image, error) {
+	st, err := os.Stat(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat SVG: %w", err)
+	}
+	if r, ok := b.renders[src]; ok && r.ModTime.Equal(st.ModTime()) && r.Size == st.Size() {
+		return r.Image, nil
+	}
+
+	img, err := inkscape.Render(b.InkscapeBinary, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	if b.renders == nil {
+		b.renders = make(map[string]render)
+	}
+	b.renders[src] = render{ModTime: st.ModTime(), Size: st.Size(), Image: img}
+	return img, nil
+}
@@ -0,0 +1,151 @@
+// Package spritesheet describes the spritesheet JSON written by build_spritesheet, and reads a built
+// spritesheet back in.
+package spritesheet
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+
+	"github.com/plane-watch/pw-silhouettes/internal/airframe"
+)
+
+type (
+
+	// Output is the schema used to generate the output JSON
+	Output struct {
+
+		// Version represents the schema version
+		Version int `json:"version"`
+
+		Metadata Metadata `json:"metadata"`
+
+		// AirframeToSprite maps an airframe ICAO (key) to a Sprite (value)
+		AirframeToSprite map[string]string `json:"airframeToSprite"`
+
+		// Sprites represents the artwork in the spritesheet. It is named after an airframe ICAO (the key).
+		Sprites map[string]Sprite `json:"sprites"`
+
+		// Airframes describes each airframe ICAO (key) that has descriptive metadata, for the UI to
+		// show. Omitted if none do.
+		Airframes map[string]Airframe `json:"airframes,omitempty"`
+	}
+
+	// Sprite represents sprite details in the output JSON
+	Sprite struct {
+		IDs       []int           `json:"ids"`
+		Scale     float64         `json:"scale"`
+		Anchor    airframe.Anchor `json:"anchor"`
+		NoRotate  bool            `json:"noRotate,omitempty"`
+		FrameTime *int            `json:"frameTime,omitempty"`
+
+		// MetresPerPixel is the real-world size of an artwork pixel, at a scale of 1, for drawing the
+		// sprite at its physical size on the map. It's only set if the airframe's dimensions are known.
+		MetresPerPixel float64 `json:"metresPerPixel,omitempty"`
+	}
+
+	// Airframe is an airframe's descriptive metadata in the output JSON
+	Airframe struct {
+		Manufacturer string            `json:"manufacturer,omitempty"`
+		Model        string            `json:"model,omitempty"`
+		CommonName   string            `json:"commonName,omitempty"`
+		Engines      *airframe.Engines `json:"engines,omitempty"`
+		Roles        []string          `json:"roles,omitempty"`
+		AltText      string            `json:"altText,omitempty"`
+	}
+
+	Metadata struct {
+		PNG          string `json:"png"`
+		SpriteWidth  int    `json:"spriteWidth"`
+		SpriteHeight int    `json:"spriteHeight"`
+	}
+)
+
+// FromFile reads a spritesheet JSON file.
+func FromFile(filename string) (*Output, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	out := new(Output)
+	err = json.Unmarshal(b, out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spritesheet: %w", err)
+	}
+	if out.Metadata.SpriteWidth <= 0 || out.Metadata.SpriteHeight <= 0 {
+		return nil, fmt.Errorf("invalid sprite size %dx%d", out.Metadata.SpriteWidth, out.Metadata.SpriteHeight)
+	}
+	return out, nil
+}
+
+// ReadPNG reads a spritesheet PNG file.
+func ReadPNG(filename string) (*image.NRGBA, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode spritesheet: %w", err)
+	}
+	if n, ok := img.(*image.NRGBA); ok {
+		return n, nil
+	}
+	n := image.NewNRGBA(img.Bounds())
+	draw.Draw(n, n.Rect, img, img.Bounds().Min, draw.Src)
+	return n, nil
+}
+
+// Cell returns the sprite with the given ID, cut from sheet.
+func (o *Output) Cell(sheet *image.NRGBA, id int) (*image.NRGBA, error) {
+	w, h := o.Metadata.SpriteWidth, o.Metadata.SpriteHeight
+	x, y, err := TopLeft(id, sheet.Rect.Dx(), w, h, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top left: %w", err)
+	}
+	r := image.Rect(x, y, x+w, y+h).Add(sheet.Rect.Min)
+	if !r.In(sheet.Rect) {
+		return nil, fmt.Errorf("sprite %d is outside the %dx%d spritesheet", id, sheet.Rect.Dx(), sheet.Rect.Dy())
+	}
+	cell := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(cell, cell.Rect, sheet, r.Min, draw.Src)
+	return cell, nil
+}
+
+// TopLeft returns the (x,y) pixel coords of the top-left corner of the sprite
+// in a uniform grid spritesheet.
+//
+// index: 0-based sprite index (left-to-right, then top-to-bottom)
+// sheetW: spritesheet width in pixels
+// frameW/frameH: frame size in pixels
+// margin: pixels before the first frame (both x and y)
+// padding: pixels between frames (both x and y)
+func TopLeft(index, sheetW, frameW, frameH, margin, padding int) (x, y int, err error) {
+	if index < 0 {
+		return 0, 0, fmt.Errorf("index must be >= 0")
+	}
+	if sheetW <= 0 || frameW <= 0 || frameH <= 0 {
+		return 0, 0, fmt.Errorf("sheetW/frameW/frameH must be > 0")
+	}
+	usableW := sheetW - 2*margin
+	if usableW <= 0 {
+		return 0, 0, fmt.Errorf("sheetW too small for margin")
+	}
+
+	// How many columns fit, accounting for padding between frames.
+	cols := (usableW + padding) / (frameW + padding)
+	if cols <= 0 {
+		return 0, 0, fmt.Errorf("no columns fit (check sheetW/frameW/margin/padding)")
+	}
+
+	col := index % cols
+	row := index / cols
+
+	x = margin + col*(frameW+padding)
+	y = margin + row*(frameH+padding)
+	return x, y, nil
+}
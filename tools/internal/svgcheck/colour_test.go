@@ -0,0 +1,114 @@
+package svgcheck
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseColour(t *testing.T) {
+	tests := []struct {
+		s    string
+		want color.NRGBA
+	}{
+		// hex
+		{"#fff", color.NRGBA{255, 255, 255, 255}},
+		{"#000", color.NRGBA{0, 0, 0, 255}},
+		{"#f80", color.NRGBA{255, 136, 0, 255}},
+		{"#ffffff", color.NRGBA{255, 255, 255, 255}},
+		{"#1a2b3c", color.NRGBA{26, 43, 60, 255}},
+		{"#f808", color.NRGBA{255, 136, 0, 136}},
+		{"#1a2b3c80", color.NRGBA{26, 43, 60, 128}},
+
+		// rgb()
+		{"rgb(255,255,255)", color.NRGBA{255, 255, 255, 255}},
+		{"rgb(0, 128, 255)", color.NRGBA{0, 128, 255, 255}},
+		{"rgb(100%, 50%, 0%)", color.NRGBA{255, 128, 0, 255}},
+		{"rgb(100%,100%,100%)", color.NRGBA{255, 255, 255, 255}},
+		{"rgb(300, -20, 0)", color.NRGBA{255, 0, 0, 255}},
+		{"rgba(0, 0, 0, 0.5)", color.NRGBA{0, 0, 0, 128}},
+		{"rgba(0, 0, 0, 50%)", color.NRGBA{0, 0, 0, 128}},
+		{"rgb(255 255 255)", color.NRGBA{255, 255, 255, 255}},
+		{"rgb(0 0 0 / 25%)", color.NRGBA{0, 0, 0, 64}},
+
+		// hsl()
+		{"hsl(0, 0%, 100%)", color.NRGBA{255, 255, 255, 255}},
+		{"hsl(120, 100%, 25%)", color.NRGBA{0, 128, 0, 255}},
+		{"hsl(-120deg 100% 50%)", color.NRGBA{0, 0, 255, 255}},
+		{"hsla(0, 100%, 50%, 0)", color.NRGBA{255, 0, 0, 0}},
+
+		// named
+		{"white", color.NRGBA{255, 255, 255, 255}},
+		{"black", color.NRGBA{0, 0, 0, 255}},
+		{"rebeccapurple", color.NRGBA{102, 51, 153, 255}},
+		{"grey", color.NRGBA{128, 128, 128, 255}},
+		{"transparent", color.NRGBA{0, 0, 0, 0}},
+
+		// case and whitespace
+		{"#FFF", color.NRGBA{255, 255, 255, 255}},
+		{"#FfFfFf", color.NRGBA{255, 255, 255, 255}},
+		{"White", color.NRGBA{255, 255, 255, 255}},
+		{"  black\t", color.NRGBA{0, 0, 0, 255}},
+		{"RGB( 255 , 255 , 255 )", color.NRGBA{255, 255, 255, 255}},
+		{"\nrgb(0,0,0)\n", color.NRGBA{0, 0, 0, 255}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColour(tt.s)
+		if err != nil {
+			t.Errorf("ParseColour(%q) failed: %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseColour(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseColourErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"none",
+		"inherit",
+		"currentColor",
+		"url(#grad)",
+		"URL(#pattern)",
+		"url(#grad) white",
+		"#ff",
+		"#fffff",
+		"#ggg",
+		"#",
+		"rgb(1, 2)",
+		"rgb(1, 2, 3, 4, 5)",
+		"rgb(a, b, c)",
+		"rgb(1, 2, 3",
+		"cmyk(0, 0, 0, 0)",
+		"notacolour",
+	}
+
+	for _, s := range tests {
+		if got, err := ParseColour(s); err == nil {
+			t.Errorf("ParseColour(%q) = %v, want an error", s, got)
+		}
+	}
+}
+
+func TestIsPaintServer(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"url(#grad)", true},
+		{"URL(#grad)", true},
+		{"  url(#pattern) white", true},
+		{"#ffffff", false},
+		{"white", false},
+		{"none", false},
+		{"rgb(0,0,0)", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPaintServer(tt.s); got != tt.want {
+			t.Errorf("IsPaintServer(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package svgcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Profile holds the expected values and rule severities used to validate a file.
+type Profile struct {
+	CanvasSize           float64             `json:"canvasSize"`
+	CanvasSizeTolerance  float64             `json:"canvasSizeTolerance"`
+	Fill                 string              `json:"fill"`
+	Stroke               string              `json:"stroke"`
+	StrokeWidth          float64             `json:"strokeWidth"`
+	StrokeWidthTolerance float64             `json:"strokeWidthTolerance"` // "close enough" tolerance for stroke-width
+	MaxNodes             int                 `json:"maxNodes"`             // 0 to disable the node budget
+	Rules                map[string]Severity `json:"rules"`
+}
+
+// DefaultProfile returns the built-in profile, which the config file's profiles override. It's the
+// only place the defaults are set, so the config file need only give what's different.
+func DefaultProfile() *Profile {
+	return &Profile{
+		CanvasSize:           70,
+		CanvasSizeTolerance:  0.01,
+		Fill:                 "#ffffff",
+		Stroke:               "#000000",
+		StrokeWidth:          0.26458333,
+		StrokeWidthTolerance: 0.0005,
+		MaxNodes:             400,
+		Rules:                map[string]Severity{},
+	}
+}
+
+// Severity returns the configured severity for a rule.
+func (p *Profile) Severity(rule string) Severity {
+	if s, ok := p.Rules[rule]; ok {
+		return s
+	}
+	return DefaultSeverities[rule]
+}
+
+// Config is the svg_check config file, which defines named profiles and which files they apply to.
+type Config struct {
+	// Profiles by name. The "default" profile starts from DefaultProfile, and the others start from
+	// the "default" profile, so only differences need be given.
+	Profiles map[string]json.RawMessage `json:"profiles"`
+
+	// Files maps silhouettes to a profile other than "default", first match wins.
+	Files []FileProfile `json:"files"`
+
+	// dir is the directory the config file is in, which the Files patterns are relative to
+	dir string
+}
+
+// FileProfile selects a profile for files matching any of the glob patterns, which are relative to
+// the config file's directory, eg: "silhouettes/GND*.svg".
+type FileProfile struct {
+	Match   []string `json:"match"`
+	Profile string   `json:"profile"`
+}
+
+const (
+	defaultProfileName = "default"
+
+	// ConfigFileName is the name of the config file, which is at the root of the repo
+	ConfigFileName = "svg_check.json"
+)
+
+// FindConfig returns the config file for an SVG: the first svg_check.json in the SVG's directory or
+// any directory above it, which is the one at the root of the repo for silhouettes. It returns ""
+// if there isn't one.
+func FindConfig(svgPath string) (string, error) {
+	dir, err := filepath.Abs(filepath.Dir(svgPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to find config: %w", err)
+	}
+	for {
+		filename := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to find config: %w", err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadConfig reads a config file. If filename is "", an empty config is returned, so only the
+// built-in defaults apply.
+func LoadConfig(filename string) (*Config, error) {
+	if filename == "" {
+		return &Config{}, nil
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	c := new(Config)
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if c.dir, err = filepath.Abs(filepath.Dir(filename)); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	for _, fp := range c.Files {
+		if _, ok := c.Profiles[fp.Profile]; !ok {
+			return nil, fmt.Errorf("config files entry refers to unknown profile %q", fp.Profile)
+		}
+		for _, m := range fp.Match {
+			if _, err := path.Match(m, ""); err != nil {
+				return nil, fmt.Errorf("invalid match pattern %q: %w", m, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// ProfileFor returns the named profile, or if name is empty, the profile selected for the file.
+func (c *Config) ProfileFor(name, file string) (*Profile, error) {
+	if name == "" {
+		name = defaultProfileName
+		// the patterns are relative to the config file, wherever the tool is run from
+		rel, err := c.relative(file)
+		if err != nil {
+			return nil, err
+		}
+	files:
+		for _, fp := range c.Files {
+			for _, m := range fp.Match {
+				if ok, _ := path.Match(m, rel); ok {
+					name = fp.Profile
+					break files
+				}
+			}
+		}
+	}
+	// named profiles are applied over the default profile, which is applied over the built-in one
+	p := DefaultProfile()
+	names := []string{defaultProfileName}
+	if name != defaultProfileName {
+		names = append(names, name)
+	}
+	for _, n := range names {
+		raw, ok := c.Profiles[n]
+		if !ok {
+			if n == defaultProfileName {
+				continue
+			}
+			return nil, fmt.Errorf("unknown profile %q", n)
+		}
+		if err := json.Unmarshal(raw, p); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal profile %q: %w", n, err)
+		}
+	}
+	if _, err := ParseColour(p.Fill); err != nil {
+		return nil, fmt.Errorf("profile %q has invalid fill: %w", name, err)
+	}
+	if _, err := ParseColour(p.Stroke); err != nil {
+		return nil, fmt.Errorf("profile %q has invalid stroke: %w", name, err)
+	}
+	for rule := range p.Rules {
+		if _, ok := DefaultSeverities[rule]; !ok {
+			return nil, fmt.Errorf("profile %q configures unknown rule %q", name, rule)
+		}
+	}
+	return p, nil
+}
+
+// relative returns file relative to the config file's directory, with forward slashes, for matching
+// against the Files patterns.
+func (c *Config) relative(file string) (string, error) {
+	if c.dir == "" {
+		return filepath.ToSlash(filepath.Clean(file)), nil
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", file, err)
+	}
+	rel, err := filepath.Rel(c.dir, abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", file, err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// ProfileForSVG returns the profile for an SVG, as svg_check chooses it: the named profile, or if
+// name is empty, the one selected for the file. Unless configFile is given, the config file is
+// found from the SVG, and if there isn't one, the built-in profile is used.
+func ProfileForSVG(configFile, name, svgPath string) (*Profile, error) {
+	if configFile == "" {
+		var err error
+		if configFile, err = FindConfig(svgPath); err != nil {
+			return nil, err
+		}
+		if configFile == "" {
+			log.Warn().Str("svg", svgPath).Msgf("no %s found beside the svg or above it, using the built-in profile", ConfigFileName)
+		}
+	}
+	c, err := LoadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return c.ProfileFor(name, svgPath)
+}
+
+// OutlineStyleKeys is OutlineStyle's properties in the order they're written.
+var OutlineStyleKeys = []string{"fill", "fill-opacity", "stroke", "stroke-width", "stroke-opacity"}
+
+// OutlineStyle returns the style of visible artwork that the profile accepts, for the tools that
+// write outlines.
+func (p *Profile) OutlineStyle() map[string]string {
+	return map[string]string{
+		"fill":           p.Fill,
+		"fill-opacity":   "1",
+		"stroke":         p.Stroke,
+		"stroke-width":   strconv.FormatFloat(p.StrokeWidth, 'f', -1, 64),
+		"stroke-opacity": "1",
+	}
+}
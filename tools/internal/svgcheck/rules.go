@@ -0,0 +1,98 @@
+// Package svgcheck holds svg_check's rules and profiles, and finds the profile for a silhouette in
+// svg_check.json, so the tools that write silhouettes can write what svg_check accepts.
+package svgcheck
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Severity is how an issue raised by a rule is treated.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityOff     Severity = "off"
+)
+
+func (s *Severity) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch Severity(v) {
+	case SeverityError, SeverityWarning, SeverityOff:
+		*s = Severity(v)
+		return nil
+	}
+	return fmt.Errorf("unknown severity %q (want error, warning or off)", v)
+}
+
+// Rule IDs, as used in the config file and in svg_check:disable comments
+const (
+	RuleSVGRoot           = "svg-root"
+	RuleCanvasSize        = "canvas-size"
+	RuleVisibleImage      = "visible-image"
+	RuleFill              = "fill"
+	RuleStroke            = "stroke"
+	RuleStrokeWidth       = "stroke-width"
+	RuleStrokeOpacity     = "stroke-opacity"
+	RuleFillOpacity       = "fill-opacity"
+	RulePathData          = "path-data"
+	RuleUnclosedPath      = "unclosed-path"
+	RuleDuplicateNode     = "duplicate-node"
+	RuleZeroLengthSegment = "zero-length-segment"
+	RuleNodeBudget        = "node-budget"
+	RuleSingleOutline     = "single-outline"
+	RuleReferenceLayer    = "reference-layer"
+	RuleOutlineLayer      = "outline-layer"
+	RuleSuppression       = "suppression"
+	RuleScript            = "script"
+	RuleExternalReference = "external-reference"
+	RuleForeignObject     = "foreign-object"
+	RuleText              = "text"
+	RuleFilter            = "filter"
+	RuleMask              = "mask"
+	RuleClipPath          = "clip-path"
+	RuleUseReference      = "use-reference"
+)
+
+// DefaultSeverities lists every rule along with its severity when not configured otherwise.
+var DefaultSeverities = map[string]Severity{
+	RuleSVGRoot:           SeverityError,
+	RuleCanvasSize:        SeverityError,
+	RuleVisibleImage:      SeverityError,
+	RuleFill:              SeverityError,
+	RuleStroke:            SeverityError,
+	RuleStrokeWidth:       SeverityError,
+	RuleStrokeOpacity:     SeverityError,
+	RuleFillOpacity:       SeverityError,
+	RulePathData:          SeverityError,
+	RuleUnclosedPath:      SeverityWarning,
+	RuleDuplicateNode:     SeverityWarning,
+	RuleZeroLengthSegment: SeverityWarning,
+	RuleNodeBudget:        SeverityWarning,
+	RuleSingleOutline:     SeverityWarning,
+	RuleReferenceLayer:    SeverityError,
+	RuleOutlineLayer:      SeverityError,
+	RuleSuppression:       SeverityError,
+	RuleScript:            SeverityError,
+	RuleExternalReference: SeverityError,
+	RuleForeignObject:     SeverityError,
+	RuleText:              SeverityError,
+	RuleFilter:            SeverityError,
+	RuleMask:              SeverityError,
+	RuleClipPath:          SeverityError,
+	RuleUseReference:      SeverityError,
+}
+
+// Unsuppressible are the rules an SVG can't disable for itself with a svg_check:disable comment, as
+// they keep contributed files safe to open. Only the config file can change their severity. The
+// suppression rule is included, so naming one of them can't be hidden either.
+var Unsuppressible = map[string]bool{
+	RuleScript:            true,
+	RuleExternalReference: true,
+	RuleForeignObject:     true,
+	RuleSuppression:       true,
+}
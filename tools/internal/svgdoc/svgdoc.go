@@ -0,0 +1,305 @@
+// Package svgdoc holds helpers shared by the tools that read silhouette SVGs and edit them in place.
+// Edits are made to the raw bytes of the file, so everything that isn't being changed
+// (formatting, attribute order, Inkscape metadata) is preserved exactly.
+package svgdoc
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Files expands a list of SVG files and directories of SVG files into a sorted list of files.
+func Files(inputs []string) ([]string, error) {
+	var files []string
+	for _, in := range inputs {
+		st, err := os.Stat(in)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			files = append(files, in)
+			continue
+		}
+		entries, err := os.ReadDir(in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dir: %w", err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".svg") {
+				files = append(files, filepath.Join(in, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Hidden returns true if an element is hidden by its own attributes or style.
+// Ancestors aren't considered: callers track the hidden state of the tree themselves.
+func Hidden(attrs []xml.Attr) bool {
+	for _, a := range attrs {
+		if a.Name.Space != "" {
+			continue
+		}
+		switch a.Name.Local {
+		case "display":
+			if strings.TrimSpace(a.Value) == "none" {
+				return true
+			}
+		case "visibility":
+			if strings.TrimSpace(a.Value) == "hidden" {
+				return true
+			}
+		case "style":
+			for _, decl := range strings.Split(a.Value, ";") {
+				k, v, ok := strings.Cut(decl, ":")
+				if !ok {
+					continue
+				}
+				k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+				if (k == "display" && v == "none") || (k == "visibility" && v == "hidden") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// PixelsPerUnit returns the number of pixels per user unit of a root <svg> element, from its width
+// and viewBox. It is 1 if either is missing or invalid.
+func PixelsPerUnit(width, viewBox string) float64 {
+	f := strings.Fields(strings.ReplaceAll(viewBox, ",", " "))
+	if len(f) != 4 {
+		return 1
+	}
+	vw, err := strconv.ParseFloat(f[2], 64)
+	if err != nil || vw <= 0 {
+		return 1
+	}
+	pw, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(width), "px"), 64)
+	if err != nil || pw <= 0 {
+		return 1
+	}
+	return pw / vw
+}
+
+// SetStyleProperties sets properties in a style attribute value, keeping the order and
+// formatting of existing declarations. New properties are appended.
+func SetStyleProperties(style string, keys []string, values map[string]string) string {
+	done := map[string]bool{}
+	decls := strings.Split(style, ";")
+	for i, decl := range decls {
+		k, _, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		k = strings.TrimSpace(k)
+		if v, ok := values[k]; ok {
+			decls[i] = k + ":" + v
+			done[k] = true
+		}
+	}
+
+	// drop a trailing empty declaration so appended ones follow directly, then restore it
+	trailing := len(decls) > 1 && strings.TrimSpace(decls[len(decls)-1]) == ""
+	if trailing {
+		decls = decls[:len(decls)-1]
+	}
+	if len(decls) == 1 && strings.TrimSpace(decls[0]) == "" {
+		decls = decls[:0]
+	}
+	for _, k := range keys {
+		if !done[k] {
+			decls = append(decls, k+":"+values[k])
+		}
+	}
+	if trailing {
+		decls = append(decls, "")
+	}
+	return strings.Join(decls, ";")
+}
+
+// RawAttr is the location of an attribute value within a raw start tag.
+type RawAttr struct {
+	NameStart            int
+	ValueStart, ValueEnd int
+	Value                string // as written, without decoding entities
+}
+
+// ScanRawAttrs finds the attributes of a raw start tag, returning where each value sits.
+// Attributes are keyed by their name as written, including any prefix (eg: "xlink:href").
+// The tag is assumed to be well-formed, as the XML decoder has already accepted it.
+func ScanRawAttrs(raw []byte) map[string]RawAttr {
+	out := map[string]RawAttr{}
+
+	i := 1 // skip '<'
+	for i < len(raw) && !isXMLSpace(raw[i]) && raw[i] != '>' && raw[i] != '/' {
+		i++ // element name
+	}
+	for i < len(raw) {
+		for i < len(raw) && isXMLSpace(raw[i]) {
+			i++
+		}
+		if i >= len(raw) || raw[i] == '>' || raw[i] == '/' {
+			break
+		}
+		nameStart := i
+		for i < len(raw) && raw[i] != '=' && !isXMLSpace(raw[i]) {
+			i++
+		}
+		name := string(raw[nameStart:i])
+		for i < len(raw) && (isXMLSpace(raw[i]) || raw[i] == '=') {
+			i++
+		}
+		if i >= len(raw) {
+			break
+		}
+		quote := raw[i]
+		i++
+		valueStart := i
+		for i < len(raw) && raw[i] != quote {
+			i++
+		}
+		out[name] = RawAttr{NameStart: nameStart, ValueStart: valueStart, ValueEnd: i, Value: string(raw[valueStart:i])}
+		i++ // closing quote
+	}
+	return out
+}
+
+// RemoveAttr returns an edit that deletes an attribute (as found by ScanRawAttrs) from a raw start tag
+// beginning at offset start, along with the whitespace before it.
+func RemoveAttr(raw []byte, start int64, a RawAttr) Edit {
+	from := a.NameStart
+	for from > 0 && isXMLSpace(raw[from-1]) {
+		from--
+	}
+	return Edit{Start: start + int64(from), End: start + int64(a.ValueEnd) + 1} // and the closing quote
+}
+
+func isXMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// Edit replaces the bytes from Start up to End with Text.
+type Edit struct {
+	Start, End int64
+	Text       []byte
+}
+
+// Apply returns a copy of data with the edits made. Edits must not overlap.
+func Apply(data []byte, edits []Edit) []byte {
+	sorted := slices.Clone(edits)
+	slices.SortFunc(sorted, func(a, b Edit) int { return cmp.Compare(a.Start, b.Start) })
+
+	out := make([]byte, 0, len(data))
+	var last int64
+	for _, e := range sorted {
+		out = append(out, data[last:e.Start]...)
+		out = append(out, e.Text...)
+		last = e.End
+	}
+	return append(out, data[last:]...)
+}
+
+// WriteFileAtomic writes data to a temporary file alongside path, then renames it into place.
+func WriteFileAtomic(path string, data []byte) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), st.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// DecodeDataURL returns the type and content of a data: URL.
+func DecodeDataURL(u string) (string, []byte, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(u), "data:")
+	if !ok {
+		return "", nil, fmt.Errorf("not a data: URL")
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", nil, fmt.Errorf("malformed data: URL")
+	}
+
+	params := strings.Split(meta, ";")
+	mimeType := strings.ToLower(strings.TrimSpace(params[0]))
+	if mimeType == "" {
+		mimeType = "text/plain"
+	}
+
+	if params[len(params)-1] == "base64" {
+		// Inkscape wraps base64 over multiple lines
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(payload), ""))
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid base64 in data: URL: %w", err)
+		}
+		return mimeType, data, nil
+	}
+
+	data, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid data: URL: %w", err)
+	}
+	return mimeType, []byte(data), nil
+}
+
+// ReferenceDir is where svg_reference keeps extracted reference images, as linked from a
+// silhouette: a "reference" directory beside the silhouettes directory, with a directory for
+// each designator.
+const ReferenceDir = "../reference"
+
+// ReferenceLink returns the path, relative to the SVG, of the image a reference image link points
+// to. Only links into ReferenceDir are accepted, in the form svg_reference writes them:
+// ../reference/<designator>/<image>. Anything else, including a link with more .. segments, which
+// could reach any file, returns false.
+func ReferenceLink(href string) (string, bool) {
+	rel, err := url.PathUnescape(strings.TrimSpace(href))
+	if err != nil || strings.ContainsAny(rel, `:\`) {
+		return "", false
+	}
+	rest, ok := strings.CutPrefix(rel, ReferenceDir+"/")
+	if !ok {
+		return "", false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 {
+		return "", false
+	}
+	for _, p := range parts {
+		if p == "" || p == "." || p == ".." {
+			return "", false
+		}
+	}
+	return filepath.FromSlash(rel), true
+}
@@ -0,0 +1,162 @@
+package svgpath
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Format writes subpaths as compact path data, with coordinates rounded to the given number of decimal places.
+// Nodes are rounded in absolute terms and then written relative to the previous node, so rounding errors
+// don't accumulate along the path.
+func Format(subpaths []Subpath, decimals int) string {
+	w := &pathWriter{decimals: decimals}
+	r := func(p Point) Point {
+		return Point{Round(p.X, decimals), Round(p.Y, decimals)}
+	}
+
+	var pen Point
+	for i, sp := range subpaths {
+		start := r(sp.Start)
+		if i == 0 {
+			w.command('M')
+			w.point(start)
+		} else {
+			w.command('m')
+			w.point(start.sub(pen))
+		}
+		pen = start
+
+		segs := sp.Segments
+		if sp.Closed && len(segs) > 0 {
+			// closepath draws the final straight line back to the start itself
+			if last := segs[len(segs)-1]; last.Cmd == 'L' && r(last.End) == start {
+				segs = segs[:len(segs)-1]
+			}
+		}
+
+		for _, seg := range segs {
+			end := r(seg.End)
+			switch seg.Cmd {
+			case 'L':
+				d := end.sub(pen)
+				switch {
+				case d.Y == 0 && d.X != 0:
+					w.command('h')
+					w.number(d.X)
+				case d.X == 0 && d.Y != 0:
+					w.command('v')
+					w.number(d.Y)
+				default:
+					w.command('l')
+					w.point(d)
+				}
+			case 'C', 'Q':
+				w.command(seg.Cmd + 'a' - 'A')
+				for _, c := range seg.Ctrl {
+					w.point(r(c).sub(pen))
+				}
+				w.point(end.sub(pen))
+			case 'A':
+				w.command('a')
+				w.number(Round(seg.RX, decimals))
+				w.number(Round(seg.RY, decimals))
+				w.number(Round(seg.Rotation, decimals))
+				w.flag(seg.LargeArc)
+				w.flag(seg.Sweep)
+				w.point(end.sub(pen))
+			}
+			pen = end
+		}
+
+		if sp.Closed {
+			w.command('z')
+			pen = start
+		}
+	}
+
+	return w.String()
+}
+
+// Decimals returns the number of decimal places needed in user units to keep precision
+// decimal places of a pixel, when there are scale pixels per user unit.
+func Decimals(scale float64, precision int) int {
+	if scale <= 0 {
+		return precision
+	}
+	return max(0, precision+int(math.Ceil(math.Log10(scale))))
+}
+
+// Round rounds v to the given number of decimal places.
+func Round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+// FormatNumber writes v with at most the given number of decimal places, as compactly as SVG allows:
+// no trailing zeros, and no leading zero before the decimal point.
+func FormatNumber(v float64, decimals int) string {
+	s := strconv.FormatFloat(v, 'f', max(decimals, 0), 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	switch {
+	case s == "-0":
+		s = "0"
+	case strings.HasPrefix(s, "0."):
+		s = s[1:]
+	case strings.HasPrefix(s, "-0."):
+		s = "-" + s[2:]
+	}
+	return s
+}
+
+func (p Point) sub(q Point) Point {
+	return Point{p.X - q.X, p.Y - q.Y}
+}
+
+// pathWriter builds path data, leaving out any separators and repeated commands that aren't needed.
+type pathWriter struct {
+	strings.Builder
+	decimals int
+	cmd      byte
+	last     string // last number written since the command, if any
+}
+
+func (w *pathWriter) command(c byte) {
+	// a repeated command is implied, except after a moveto (where it would become a lineto).
+	// Arcs are always written out as some renderers mishandle repeated arcs.
+	if c == w.cmd && c != 'm' && c != 'M' && c != 'a' {
+		return
+	}
+	w.WriteByte(c)
+	w.cmd = c
+	w.last = ""
+}
+
+func (w *pathWriter) number(v float64) {
+	w.write(FormatNumber(v, w.decimals))
+}
+
+func (w *pathWriter) flag(b bool) {
+	if b {
+		w.write("1")
+	} else {
+		w.write("0")
+	}
+}
+
+func (w *pathWriter) point(p Point) {
+	w.number(p.X)
+	w.number(p.Y)
+}
+
+func (w *pathWriter) write(s string) {
+	// a separator is only needed if s could be read as part of the previous number
+	if w.last != "" && s[0] != '-' && !(s[0] == '.' && strings.Contains(w.last, ".")) {
+		w.WriteByte(' ')
+	}
+	w.WriteString(s)
+	w.last = s
+}
@@ -0,0 +1,159 @@
+package svgpath
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        string
+		decimals int
+		want     string
+	}{
+		{"absolute lines become relative", "M10 10 L20 10 L20 20 L10 20 Z", 3, "M10 10h10v10h-10z"},
+		{"relative lines stay relative", "m10 10 l10 0 l0 10 l-10 0 z", 3, "M10 10h10v10h-10z"},
+		{"diagonal line", "M0 0 L3 4", 3, "M0 0l3 4"},
+		{"closing line is left to closepath", "M0 0 H10 V10 L0 0 Z", 3, "M0 0h10v10z"},
+		{"closing line is kept on an open path", "M0 0 H10 V10 L0 0", 3, "M0 0h10v10l-10-10"},
+		{"repeated commands are implied", "M0 0 L1 2 L2 4 L3 6", 3, "M0 0l1 2 1 2 1 2"},
+		{"curves", "M0 0 C0 5 5 10 10 10 Q15 10 20 0", 3, "M0 0c0 5 5 10 10 10q5 0 10-10"},
+		{"smooth curves are written out in full", "M0 0 C0 5 5 10 10 10 S20 5 20 0", 3, "M0 0c0 5 5 10 10 10 5 0 10-5 10-10"},
+		{"arcs are always written with their command", "M10 10 A5 5 0 0 1 20 10 A5 6 30 1 0 10 10", 3, "M10 10a5 5 0 0 1 10 0a5 6 30 1 0-10 0"},
+		{"no leading zero or separator before a packed decimal", "M0.5 0.5 L0.25 -0.75", 3, "M.5.5l-.25-1.25"},
+		{"rounded to decimals", "M1.23456 2.34567 L3.45678 4.56789", 2, "M1.23 2.35l2.23 2.22"},
+		{"tiny values round to zero", "M1e-3 0 L10 1e-4", 2, "M0 0h10"},
+		{"second subpath uses a relative moveto", "M10 10 H20 Z M30 30 H40 Z", 3, "M10 10h10zm20 20h10z"},
+		{"moveto is not implied after a moveto", "M0 0 m5 5 h1", 3, "M0 0m5 5h1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sps, err := Parse(tt.d)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.d, err)
+			}
+			if got := Format(sps, tt.decimals); got != tt.want {
+				t.Errorf("Format(%q, %d) = %q, want %q", tt.d, tt.decimals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	paths := []string{
+		"M10 10 L20 10 L20 20 L10 20 Z",
+		"m10 10 l10 0 l0 10 l-10 0 z m20 0 h5 v5 h-5 z",
+		"M0 0 C0 5 5 10 10 10 S20 5 20 0 Q25 -5 30 0 T40 0 Z",
+		"M10 10 A5 5 0 0 1 20 10 a5 6 30 1 0 -10 0 z",
+		"M.5.5L1-2-.5.25 1e-3 5e1z",
+		"M35 5 c-3 0-4 8-4 14 l-20 12 v4 l20-4 1 14-6 5v3l9-2 9 2v-3l-6-5 1-14 20 4v-4l-20-12c0-6-1-14-4-14z",
+	}
+
+	for _, d := range paths {
+		want, err := Parse(d)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", d, err)
+		}
+		out := Format(want, 6)
+		got, err := Parse(out)
+		if err != nil {
+			t.Fatalf("Parse(Format(%q)) = Parse(%q) failed: %v", d, out, err)
+		}
+		if !samePath(got, want) {
+			t.Errorf("Parse(Format(%q)) = %+v (from %q), want %+v", d, got, out, want)
+		}
+	}
+}
+
+// samePath compares subpaths by where they draw, allowing for the closing line that Format leaves to closepath.
+func samePath(a, b []Subpath) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		as, bs := a[i].Segments, b[i].Segments
+		if a[i].Closed && len(bs) == len(as)+1 {
+			if last := bs[len(bs)-1]; last.Cmd == 'L' && last.End.Near(b[i].Start) {
+				bs = bs[:len(bs)-1]
+			}
+		}
+		if !a[i].Start.Near(b[i].Start) || a[i].Closed != b[i].Closed || len(as) != len(bs) {
+			return false
+		}
+		for j := range as {
+			if !sameSegment(as[j], bs[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sameSegment(a, b Segment) bool {
+	if a.Cmd != b.Cmd || !a.Start.Near(b.Start) || !a.End.Near(b.End) || len(a.Ctrl) != len(b.Ctrl) {
+		return false
+	}
+	for k := range a.Ctrl {
+		if !a.Ctrl[k].Near(b.Ctrl[k]) {
+			return false
+		}
+	}
+	return a.RX == b.RX && a.RY == b.RY && a.Rotation == b.Rotation && a.LargeArc == b.LargeArc && a.Sweep == b.Sweep
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		v        float64
+		decimals int
+		want     string
+	}{
+		{0, 3, "0"},
+		{1, 3, "1"},
+		{10, 0, "10"},
+		{0.5, 3, ".5"},
+		{-0.5, 3, "-.5"},
+		{1.25, 3, "1.25"},
+		{1.2345, 2, "1.23"},
+		{-0.0001, 2, "0"},
+		{1e-3, 3, ".001"},
+		{1.5, -1, "2"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatNumber(tt.v, tt.decimals); got != tt.want {
+			t.Errorf("FormatNumber(%v, %d) = %q, want %q", tt.v, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestDecimals(t *testing.T) {
+	tests := []struct {
+		scale     float64
+		precision int
+		want      int
+	}{
+		{1, 3, 3},
+		{10, 3, 4},
+		{3.7795, 2, 3},
+		{0.05, 3, 2},
+		{0.0001, 2, 0},
+		{0, 3, 3},
+	}
+
+	for _, tt := range tests {
+		if got := Decimals(tt.scale, tt.precision); got != tt.want {
+			t.Errorf("Decimals(%v, %d) = %d, want %d", tt.scale, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	if got := Round(math.Pi, 3); got != 3.142 {
+		t.Errorf("Round(Pi, 3) = %v, want 3.142", got)
+	}
+	if got := Round(-2.5, 0); got != -3 {
+		t.Errorf("Round(-2.5, 0) = %v, want -3", got)
+	}
+}
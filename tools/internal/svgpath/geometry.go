@@ -0,0 +1,145 @@
+package svgpath
+
+import "math"
+
+func (p Point) add(q Point) Point {
+	return Point{p.X + q.X, p.Y + q.Y}
+}
+
+func (p Point) scale(f float64) Point {
+	return Point{p.X * f, p.Y * f}
+}
+
+func (p Point) dot(q Point) float64 {
+	return p.X*q.X + p.Y*q.Y
+}
+
+func (p Point) length() float64 {
+	return math.Hypot(p.X, p.Y)
+}
+
+// unit returns p scaled to a length of 1, or the zero point if p has no length.
+func (p Point) unit() Point {
+	l := p.length()
+	if l == 0 {
+		return Point{}
+	}
+	return p.scale(1 / l)
+}
+
+// At returns the point on the segment at t, from 0 (its start) to 1 (its end).
+func (s Segment) At(t float64) Point {
+	mt := 1 - t
+	switch s.Cmd {
+	case 'C':
+		return s.Start.scale(mt * mt * mt).
+			add(s.Ctrl[0].scale(3 * mt * mt * t)).
+			add(s.Ctrl[1].scale(3 * mt * t * t)).
+			add(s.End.scale(t * t * t))
+	case 'Q':
+		return s.Start.scale(mt * mt).
+			add(s.Ctrl[0].scale(2 * mt * t)).
+			add(s.End.scale(t * t))
+	case 'A':
+		if a, ok := s.arc(); ok {
+			return a.at(a.theta + t*a.delta)
+		}
+	}
+	return s.Start.scale(mt).add(s.End.scale(t))
+}
+
+// Length returns the approximate length of the segment.
+func (s Segment) Length() float64 {
+	if s.Cmd == 'L' {
+		return s.End.sub(s.Start).length()
+	}
+	const steps = 16
+	var l float64
+	prev := s.Start
+	for i := 1; i <= steps; i++ {
+		p := s.At(float64(i) / steps)
+		l += p.sub(prev).length()
+		prev = p
+	}
+	return l
+}
+
+// StartTangent returns the unit direction the segment leaves its start in.
+func (s Segment) StartTangent() Point {
+	for _, t := range []float64{1e-6, 1e-3, 0.5, 1} {
+		if d := s.At(t).sub(s.Start).unit(); d != (Point{}) {
+			return d
+		}
+	}
+	return Point{}
+}
+
+// EndTangent returns the unit direction the segment arrives at its end in.
+func (s Segment) EndTangent() Point {
+	for _, t := range []float64{1 - 1e-6, 1 - 1e-3, 0.5, 0} {
+		if d := s.End.sub(s.At(t)).unit(); d != (Point{}) {
+			return d
+		}
+	}
+	return Point{}
+}
+
+// centreArc is an arc segment in centre parameterisation, as described in the SVG implementation notes.
+type centreArc struct {
+	centre       Point
+	rx, ry       float64
+	cos, sin     float64 // of the x-axis rotation
+	theta, delta float64 // start angle and sweep, in radians
+}
+
+func (a centreArc) at(angle float64) Point {
+	x, y := a.rx*math.Cos(angle), a.ry*math.Sin(angle)
+	return Point{a.centre.X + a.cos*x - a.sin*y, a.centre.Y + a.sin*x + a.cos*y}
+}
+
+// arc converts an arc segment to centre parameterisation. It returns false for arcs that are
+// drawn as a straight line: zero radii, or the same start and end.
+func (s Segment) arc() (centreArc, bool) {
+	rx, ry := math.Abs(s.RX), math.Abs(s.RY)
+	if rx == 0 || ry == 0 || s.Start == s.End {
+		return centreArc{}, false
+	}
+	phi := s.Rotation * math.Pi / 180
+	a := centreArc{cos: math.Cos(phi), sin: math.Sin(phi)}
+
+	// the midpoint between start and end, in the ellipse's rotated frame
+	dx, dy := (s.Start.X-s.End.X)/2, (s.Start.Y-s.End.Y)/2
+	x1 := a.cos*dx + a.sin*dy
+	y1 := -a.sin*dx + a.cos*dy
+
+	// scale up radii that are too small to reach the end
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx *= math.Sqrt(l)
+		ry *= math.Sqrt(l)
+	}
+	a.rx, a.ry = rx, ry
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	f := math.Sqrt(max(0, num/den))
+	if s.LargeArc == s.Sweep {
+		f = -f
+	}
+	cx1, cy1 := f*rx*y1/ry, -f*ry*x1/rx
+	a.centre = Point{
+		a.cos*cx1 - a.sin*cy1 + (s.Start.X+s.End.X)/2,
+		a.sin*cx1 + a.cos*cy1 + (s.Start.Y+s.End.Y)/2,
+	}
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	a.theta = angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	a.delta = angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !s.Sweep && a.delta > 0 {
+		a.delta -= 2 * math.Pi
+	} else if s.Sweep && a.delta < 0 {
+		a.delta += 2 * math.Pi
+	}
+	return a, true
+}
@@ -0,0 +1,211 @@
+package svgpath
+
+import "math"
+
+// cornerAngle is the change of direction (in radians) above which a node is kept as a corner,
+// rather than smoothed over by curve fitting.
+const cornerAngle = 30 * math.Pi / 180
+
+// maxSamples limits how many points each segment is sampled at when fitting.
+const maxSamples = 32
+
+// Simplify returns the subpaths redrawn with as few nodes as it can, while staying within tol
+// (in user units) of the original outline. Runs of nodes that lie on a straight line become a
+// single line, and smooth runs are refitted with cubic Béziers (using the method from Philip J.
+// Schneider's "An Algorithm for Automatically Fitting Digitized Curves", Graphics Gems, 1990).
+// Corners and the start of each subpath stay where they are. Subpaths that don't end up with
+// fewer nodes are returned unchanged.
+func Simplify(subpaths []Subpath, tol float64) []Subpath {
+	out := make([]Subpath, len(subpaths))
+	for i, sp := range subpaths {
+		out[i] = sp
+		if s := simplifySubpath(sp, tol); s.Nodes() < sp.Nodes() {
+			out[i] = s
+		}
+	}
+	return out
+}
+
+func simplifySubpath(sp Subpath, tol float64) Subpath {
+	segs := make([]Segment, 0, len(sp.Segments)+1)
+	for _, seg := range sp.Segments {
+		if !seg.ZeroLength() {
+			segs = append(segs, seg)
+		}
+	}
+	// make the closing line explicit so it's simplified with the rest; Format leaves it out again
+	if sp.Closed && len(segs) > 0 && !segs[len(segs)-1].End.Near(sp.Start) {
+		segs = append(segs, Segment{Cmd: 'L', Start: segs[len(segs)-1].End, End: sp.Start})
+	}
+
+	out := Subpath{Start: sp.Start, Closed: sp.Closed}
+	for len(segs) > 0 {
+		n := 1
+		for n < len(segs) && !isCorner(segs[n-1], segs[n]) {
+			n++
+		}
+		out.Segments = append(out.Segments, fitRun(segs[:n], tol)...)
+		segs = segs[n:]
+	}
+	return out
+}
+
+// isCorner returns true if the path changes direction sharply between two segments.
+func isCorner(a, b Segment) bool {
+	return a.EndTangent().dot(b.StartTangent()) < math.Cos(cornerAngle)
+}
+
+// fitRun refits a run of segments that meet smoothly.
+func fitRun(segs []Segment, tol float64) []Segment {
+	if len(segs) == 1 {
+		return segs
+	}
+
+	pts := []Point{segs[0].Start}
+	for _, s := range segs {
+		n := min(max(int(math.Ceil(s.Length()/tol)), 1), maxSamples)
+		for i := 1; i <= n; i++ {
+			pts = append(pts, s.At(float64(i)/float64(n)))
+		}
+	}
+
+	t1 := segs[0].StartTangent()
+	t2 := segs[len(segs)-1].EndTangent().scale(-1)
+	return fitCubic(pts, t1, t2, tol)
+}
+
+// fitCubic fits points with lines and cubic Béziers. t1 is the direction the curve leaves the first
+// point in, and t2 the direction from the last point back into the curve.
+func fitCubic(pts []Point, t1, t2 Point, tol float64) []Segment {
+	start, end := pts[0], pts[len(pts)-1]
+	if maxLineDistance(pts) <= tol {
+		return []Segment{{Cmd: 'L', Start: start, End: end}}
+	}
+
+	u := chordLengths(pts)
+	bez := generateBezier(pts, u, t1, t2)
+	dist, split := maxError(pts, bez, u)
+	if dist <= tol {
+		return []Segment{bez}
+	}
+
+	// close enough that improving the parameters may be all it takes
+	if dist <= 4*tol {
+		for range 4 {
+			u = reparameterise(bez, pts, u)
+			bez = generateBezier(pts, u, t1, t2)
+			if dist, split = maxError(pts, bez, u); dist <= tol {
+				return []Segment{bez}
+			}
+		}
+	}
+
+	// split at the worst point, keeping the curve smooth through it
+	centre := pts[split-1].sub(pts[split+1]).unit()
+	if centre == (Point{}) {
+		centre = pts[split-1].sub(pts[split]).unit()
+	}
+	left := fitCubic(pts[:split+1], t1, centre, tol)
+	right := fitCubic(pts[split:], centre.scale(-1), t2, tol)
+	return append(left, right...)
+}
+
+// maxLineDistance returns how far the points stray from a straight line between the first and last.
+func maxLineDistance(pts []Point) float64 {
+	a, b := pts[0], pts[len(pts)-1]
+	ab := b.sub(a)
+	l2 := ab.dot(ab)
+	var worst float64
+	for _, p := range pts[1 : len(pts)-1] {
+		t := 0.0
+		if l2 > 0 {
+			t = min(max(p.sub(a).dot(ab)/l2, 0), 1)
+		}
+		worst = max(worst, p.sub(a.add(ab.scale(t))).length())
+	}
+	return worst
+}
+
+// chordLengths assigns each point a parameter from 0 to 1, by its distance along the points.
+func chordLengths(pts []Point) []float64 {
+	u := make([]float64, len(pts))
+	for i := 1; i < len(pts); i++ {
+		u[i] = u[i-1] + pts[i].sub(pts[i-1]).length()
+	}
+	total := u[len(u)-1]
+	for i := range u {
+		if total > 0 {
+			u[i] /= total
+		} else {
+			u[i] = float64(i) / float64(len(u)-1)
+		}
+	}
+	return u
+}
+
+// generateBezier finds the least-squares cubic through the points at parameters u, with the given end tangents.
+func generateBezier(pts []Point, u []float64, t1, t2 Point) Segment {
+	start, end := pts[0], pts[len(pts)-1]
+
+	var c00, c01, c11, x0, x1 float64
+	for i, p := range pts {
+		mt := 1 - u[i]
+		b0, b1, b2, b3 := mt*mt*mt, 3*mt*mt*u[i], 3*mt*u[i]*u[i], u[i]*u[i]*u[i]
+		a1, a2 := t1.scale(b1), t2.scale(b2)
+		c00 += a1.dot(a1)
+		c01 += a1.dot(a2)
+		c11 += a2.dot(a2)
+		rest := p.sub(start.scale(b0 + b1)).sub(end.scale(b2 + b3))
+		x0 += a1.dot(rest)
+		x1 += a2.dot(rest)
+	}
+
+	var alpha1, alpha2 float64
+	if det := c00*c11 - c01*c01; det != 0 {
+		alpha1 = (x0*c11 - c01*x1) / det
+		alpha2 = (c00*x1 - c01*x0) / det
+	}
+
+	// fall back to a third of the chord if the fit is degenerate
+	chord := end.sub(start).length()
+	if eps := 1e-6 * chord; alpha1 < eps || alpha2 < eps {
+		alpha1, alpha2 = chord/3, chord/3
+	}
+
+	return Segment{Cmd: 'C', Start: start, Ctrl: []Point{start.add(t1.scale(alpha1)), end.add(t2.scale(alpha2))}, End: end}
+}
+
+// maxError returns the furthest any point is from the curve at its parameter, and which point that is.
+func maxError(pts []Point, bez Segment, u []float64) (float64, int) {
+	worst, split := 0.0, len(pts)/2
+	for i := 1; i < len(pts)-1; i++ {
+		if d := bez.At(u[i]).sub(pts[i]).length(); d > worst {
+			worst, split = d, i
+		}
+	}
+	return worst, split
+}
+
+// reparameterise improves each point's parameter with a step of Newton-Raphson, moving it towards
+// the nearest point on the curve.
+func reparameterise(bez Segment, pts []Point, u []float64) []float64 {
+	p0, p1, p2, p3 := bez.Start, bez.Ctrl[0], bez.Ctrl[1], bez.End
+
+	// first and second derivatives of the cubic
+	d1 := [3]Point{p1.sub(p0).scale(3), p2.sub(p1).scale(3), p3.sub(p2).scale(3)}
+	d2 := [2]Point{d1[1].sub(d1[0]).scale(2), d1[2].sub(d1[1]).scale(2)}
+
+	out := make([]float64, len(u))
+	for i, t := range u {
+		mt := 1 - t
+		q := bez.At(t).sub(pts[i])
+		q1 := d1[0].scale(mt * mt).add(d1[1].scale(2 * mt * t)).add(d1[2].scale(t * t))
+		q2 := d2[0].scale(mt).add(d2[1].scale(t))
+
+		out[i] = t
+		if den := q1.dot(q1) + q.dot(q2); den != 0 {
+			out[i] = min(max(t-q.dot(q1)/den, 0), 1)
+		}
+	}
+	return out
+}
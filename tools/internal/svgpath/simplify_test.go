@@ -0,0 +1,142 @@
+package svgpath
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// circle returns path data for a closed polygon of n nodes around a circle.
+func circle(cx, cy, r float64, n int) string {
+	var b strings.Builder
+	for i := range n {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		cmd := "L"
+		if i == 0 {
+			cmd = "M"
+		}
+		fmt.Fprintf(&b, "%s%g %g ", cmd, cx+r*math.Cos(a), cy+r*math.Sin(a))
+	}
+	b.WriteString("Z")
+	return b.String()
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name string
+		d    string
+		tol  float64
+		// want is the formatted result, or empty to only check the node count and tolerance
+		want     string
+		maxNodes int
+	}{
+		{
+			name: "nodes on a straight line are removed",
+			d:    "M0 0 L5 0 L10 0 L10 5 L10 10 L0 10 Z",
+			tol:  0.1,
+			want: "M0 0h10v10h-10z",
+		},
+		{
+			name: "nearly straight nodes within tolerance are removed",
+			d:    "M0 0 L5 0.05 L10 0 L10 10 L0 10 Z",
+			tol:  0.1,
+			want: "M0 0h10v10h-10z",
+		},
+		{
+			name: "nodes further than tolerance from the line are kept",
+			d:    "M0 0 L5 1 L10 0 L10 10 L0 10 Z",
+			tol:  0.1,
+			want: "M0 0l5 1 5-1v10h-10z",
+		},
+		{
+			name: "zero-length segments are dropped",
+			d:    "M0 0 L10 0 L10 0 L10 10 L0 10 Z",
+			tol:  0.1,
+			want: "M0 0h10v10h-10z",
+		},
+		{
+			name: "corners are kept",
+			d:    "M0 0 L10 0 L10 10 L0 10 Z",
+			tol:  0.1,
+			want: "M0 0h10v10h-10z",
+		},
+		{
+			name:     "smooth runs are refitted with curves",
+			d:        circle(35, 35, 20, 64),
+			tol:      0.1,
+			maxNodes: 16,
+		},
+		{
+			name: "open subpaths stay open",
+			d:    "M0 0 L5 0 L10 0",
+			tol:  0.1,
+			want: "M0 0h10",
+		},
+		{
+			name: "subpaths that can't lose a node are unchanged",
+			d:    "M0 0 L10 0 L10 10",
+			tol:  0.1,
+			want: "M0 0h10v10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sps, err := Parse(tt.d)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.d, err)
+			}
+			got := Simplify(sps, tt.tol)
+
+			if tt.want != "" {
+				if s := Format(got, 3); s != tt.want {
+					t.Errorf("Simplify(%q, %v) = %q, want %q", tt.d, tt.tol, s, tt.want)
+				}
+			}
+
+			nodes := 0
+			for _, sp := range got {
+				nodes += sp.Nodes()
+			}
+			if tt.maxNodes > 0 && nodes > tt.maxNodes {
+				t.Errorf("Simplify(%q, %v) has %d nodes, want at most %d", tt.d, tt.tol, nodes, tt.maxNodes)
+			}
+
+			if d := maxDistance(got, sps); d > tt.tol {
+				t.Errorf("Simplify(%q, %v) strays %v from the original, want at most %v", tt.d, tt.tol, d, tt.tol)
+			}
+		})
+	}
+}
+
+// maxDistance returns roughly how far the outline a strays from the outline b, by sampling a
+// and measuring each sample's distance to the nearest of many samples of b.
+func maxDistance(a, b []Subpath) float64 {
+	sample := func(sps []Subpath, n int) []Point {
+		var pts []Point
+		for _, sp := range sps {
+			segs := sp.Segments
+			if sp.Closed && len(segs) > 0 {
+				segs = append(segs, Segment{Cmd: 'L', Start: segs[len(segs)-1].End, End: sp.Start})
+			}
+			for _, s := range segs {
+				for i := 0; i <= n; i++ {
+					pts = append(pts, s.At(float64(i)/float64(n)))
+				}
+			}
+		}
+		return pts
+	}
+
+	bs := sample(b, 200)
+	worst := 0.0
+	for _, p := range sample(a, 20) {
+		best := math.Inf(1)
+		for _, q := range bs {
+			best = min(best, p.sub(q).length())
+		}
+		worst = max(worst, best)
+	}
+	return worst
+}
@@ -0,0 +1,339 @@
+// Package svgpath parses and formats SVG path data and transforms.
+package svgpath
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// NodeTol is the distance (in user units) below which two nodes are considered
+// to be at the same position.
+const NodeTol = 1e-6
+
+// Point is a position in user units.
+type Point struct {
+	X, Y float64
+}
+
+// Near returns true if p and q are within NodeTol of each other.
+func (p Point) Near(q Point) bool {
+	return math.Abs(p.X-q.X) <= NodeTol && math.Abs(p.Y-q.Y) <= NodeTol
+}
+
+// Segment is a single drawing command from a path, converted to absolute
+// coordinates. Cmd is one of 'L', 'C', 'Q' or 'A'; H/V are folded into L and
+// the smooth variants (S/T) have their reflected control point made explicit.
+type Segment struct {
+	Cmd   byte
+	Start Point
+	Ctrl  []Point // control points for C/Q, empty otherwise
+	End   Point
+
+	// Arc parameters, only meaningful when Cmd is 'A'
+	RX, RY, Rotation float64
+	LargeArc, Sweep  bool
+}
+
+// ZeroLength returns true if the segment does not move the pen at all.
+func (s Segment) ZeroLength() bool {
+	if !s.Start.Near(s.End) {
+		return false
+	}
+	for _, c := range s.Ctrl {
+		if !c.Near(s.Start) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subpath is a run of segments started by a moveto.
+type Subpath struct {
+	Start    Point
+	Segments []Segment
+	Closed   bool
+}
+
+// Nodes returns the number of nodes in the subpath, as Inkscape's node tool would show them.
+func (sp Subpath) Nodes() int {
+	n := 1 + len(sp.Segments)
+	if sp.Closed && len(sp.Segments) > 0 && sp.Segments[len(sp.Segments)-1].End.Near(sp.Start) {
+		// the final node sits on top of the first and is shown as one
+		n--
+	}
+	return n
+}
+
+// Parse parses an SVG path "d" attribute into absolute subpaths.
+func Parse(d string) ([]Subpath, error) {
+	p := &pathParser{s: d}
+
+	var (
+		out      []Subpath
+		cur      *Subpath
+		pen      Point
+		lastCmd  byte
+		lastCtrl Point // last control point, for S/T reflection
+	)
+
+	for {
+		p.skipSeparators()
+		if p.eof() {
+			break
+		}
+
+		cmd := p.s[p.i]
+		if !isPathCommand(cmd) {
+			if lastCmd == 0 {
+				return nil, fmt.Errorf("path data must start with a moveto (got %q at offset %d)", cmd, p.i)
+			}
+			// implicit repeat of the previous command
+			cmd = lastCmd
+			switch cmd {
+			case 'M':
+				cmd = 'L'
+			case 'm':
+				cmd = 'l'
+			case 'Z', 'z':
+				return nil, fmt.Errorf("unexpected number after closepath at offset %d", p.i)
+			}
+		} else {
+			p.i++
+		}
+
+		if lastCmd == 0 && cmd != 'M' && cmd != 'm' {
+			return nil, fmt.Errorf("path data must start with a moveto (got %q)", cmd)
+		}
+
+		rel := cmd >= 'a' && cmd <= 'z'
+		abs := func(q Point) Point {
+			if rel {
+				return Point{pen.X + q.X, pen.Y + q.Y}
+			}
+			return q
+		}
+
+		// after a closepath, a drawing command implicitly starts a new Subpath at the closed start
+		if cur != nil && cur.Closed && cmd != 'M' && cmd != 'm' && cmd != 'Z' && cmd != 'z' {
+			out = append(out, Subpath{Start: cur.Start})
+			cur = &out[len(out)-1]
+		}
+
+		var seg *Segment
+
+		switch cmd {
+		case 'M', 'm':
+			q, err := p.point()
+			if err != nil {
+				return nil, err
+			}
+			pen = abs(q)
+			out = append(out, Subpath{Start: pen})
+			cur = &out[len(out)-1]
+
+		case 'Z', 'z':
+			cur.Closed = true
+			pen = cur.Start
+
+		case 'L', 'l':
+			q, err := p.point()
+			if err != nil {
+				return nil, err
+			}
+			seg = &Segment{Cmd: 'L', Start: pen, End: abs(q)}
+
+		case 'H', 'h':
+			x, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+			if rel {
+				x += pen.X
+			}
+			seg = &Segment{Cmd: 'L', Start: pen, End: Point{x, pen.Y}}
+
+		case 'V', 'v':
+			y, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+			if rel {
+				y += pen.Y
+			}
+			seg = &Segment{Cmd: 'L', Start: pen, End: Point{pen.X, y}}
+
+		case 'C', 'c':
+			pts, err := p.points(3)
+			if err != nil {
+				return nil, err
+			}
+			seg = &Segment{Cmd: 'C', Start: pen, Ctrl: []Point{abs(pts[0]), abs(pts[1])}, End: abs(pts[2])}
+
+		case 'S', 's':
+			pts, err := p.points(2)
+			if err != nil {
+				return nil, err
+			}
+			c1 := pen
+			if lastCmd == 'C' || lastCmd == 'c' || lastCmd == 'S' || lastCmd == 's' {
+				c1 = Point{2*pen.X - lastCtrl.X, 2*pen.Y - lastCtrl.Y}
+			}
+			seg = &Segment{Cmd: 'C', Start: pen, Ctrl: []Point{c1, abs(pts[0])}, End: abs(pts[1])}
+
+		case 'Q', 'q':
+			pts, err := p.points(2)
+			if err != nil {
+				return nil, err
+			}
+			seg = &Segment{Cmd: 'Q', Start: pen, Ctrl: []Point{abs(pts[0])}, End: abs(pts[1])}
+
+		case 'T', 't':
+			q, err := p.point()
+			if err != nil {
+				return nil, err
+			}
+			c := pen
+			if lastCmd == 'Q' || lastCmd == 'q' || lastCmd == 'T' || lastCmd == 't' {
+				c = Point{2*pen.X - lastCtrl.X, 2*pen.Y - lastCtrl.Y}
+			}
+			seg = &Segment{Cmd: 'Q', Start: pen, Ctrl: []Point{c}, End: abs(q)}
+
+		case 'A', 'a':
+			var v [3]float64
+			for i := range v {
+				n, err := p.number()
+				if err != nil {
+					return nil, err
+				}
+				v[i] = n
+			}
+			large, err := p.flag()
+			if err != nil {
+				return nil, err
+			}
+			sweep, err := p.flag()
+			if err != nil {
+				return nil, err
+			}
+			q, err := p.point()
+			if err != nil {
+				return nil, err
+			}
+			seg = &Segment{Cmd: 'A', Start: pen, End: abs(q), RX: v[0], RY: v[1], Rotation: v[2], LargeArc: large, Sweep: sweep}
+		}
+
+		if seg != nil {
+			cur.Segments = append(cur.Segments, *seg)
+			pen = seg.End
+			if len(seg.Ctrl) > 0 {
+				lastCtrl = seg.Ctrl[len(seg.Ctrl)-1]
+			}
+		}
+		lastCmd = cmd
+	}
+
+	return out, nil
+}
+
+func isPathCommand(c byte) bool {
+	return strings.IndexByte("MmZzLlHhVvCcSsQqTtAa", c) >= 0
+}
+
+// pathParser is a small scanner over SVG path data.
+type pathParser struct {
+	s string
+	i int
+}
+
+func (p *pathParser) eof() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *pathParser) skipSeparators() {
+	for !p.eof() {
+		switch p.s[p.i] {
+		case ' ', '\t', '\n', '\r', '\f', ',':
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+// number reads a single SVG number, which may be packed against its neighbours (eg: "1.5.5" or "1-2").
+func (p *pathParser) number() (float64, error) {
+	p.skipSeparators()
+	start := p.i
+	if !p.eof() && (p.s[p.i] == '+' || p.s[p.i] == '-') {
+		p.i++
+	}
+	digits := p.digits()
+	if !p.eof() && p.s[p.i] == '.' {
+		p.i++
+		digits += p.digits()
+	}
+	if digits == 0 {
+		return 0, fmt.Errorf("expected number at offset %d", start)
+	}
+	if !p.eof() && (p.s[p.i] == 'e' || p.s[p.i] == 'E') {
+		save := p.i
+		p.i++
+		if !p.eof() && (p.s[p.i] == '+' || p.s[p.i] == '-') {
+			p.i++
+		}
+		if p.digits() == 0 {
+			// not an exponent after all
+			p.i = save
+		}
+	}
+	return strconv.ParseFloat(p.s[start:p.i], 64)
+}
+
+func (p *pathParser) digits() int {
+	n := 0
+	for !p.eof() && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+		p.i++
+		n++
+	}
+	return n
+}
+
+// flag reads an arc flag, which is a single '0' or '1' that need not be separated from what follows.
+func (p *pathParser) flag() (bool, error) {
+	p.skipSeparators()
+	if p.eof() {
+		return false, fmt.Errorf("expected arc flag at end of path data")
+	}
+	c := p.s[p.i]
+	if c != '0' && c != '1' {
+		return false, fmt.Errorf("expected arc flag at offset %d (got %q)", p.i, c)
+	}
+	p.i++
+	return c == '1', nil
+}
+
+func (p *pathParser) point() (Point, error) {
+	x, err := p.number()
+	if err != nil {
+		return Point{}, err
+	}
+	y, err := p.number()
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{x, y}, nil
+}
+
+func (p *pathParser) points(n int) ([]Point, error) {
+	out := make([]Point, 0, n)
+	for range n {
+		q, err := p.point()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, q)
+	}
+	return out, nil
+}
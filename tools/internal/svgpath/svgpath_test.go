@@ -0,0 +1,204 @@
+package svgpath
+
+import (
+	"reflect"
+	"testing"
+)
+
+func line(x0, y0, x1, y1 float64) Segment {
+	return Segment{Cmd: 'L', Start: Point{x0, y0}, End: Point{x1, y1}}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		d    string
+		want []Subpath
+	}{
+		{
+			name: "absolute lines",
+			d:    "M 10,10 L 20,10 L 20,20 Z",
+			want: []Subpath{{Start: Point{10, 10}, Closed: true, Segments: []Segment{line(10, 10, 20, 10), line(20, 10, 20, 20)}}},
+		},
+		{
+			name: "relative lines",
+			d:    "m10 10 l10 0 l0 10 z",
+			want: []Subpath{{Start: Point{10, 10}, Closed: true, Segments: []Segment{line(10, 10, 20, 10), line(20, 10, 20, 20)}}},
+		},
+		{
+			name: "implicit lineto after moveto",
+			d:    "M10 10 20 10 20 20",
+			want: []Subpath{{Start: Point{10, 10}, Segments: []Segment{line(10, 10, 20, 10), line(20, 10, 20, 20)}}},
+		},
+		{
+			name: "implicit relative lineto after relative moveto",
+			d:    "m10 10 10 0 0 10",
+			want: []Subpath{{Start: Point{10, 10}, Segments: []Segment{line(10, 10, 20, 10), line(20, 10, 20, 20)}}},
+		},
+		{
+			name: "repeated implicit coordinates",
+			d:    "M0 0 h10 5 v10 5 l-5 0 -10 0",
+			want: []Subpath{{Start: Point{0, 0}, Segments: []Segment{
+				line(0, 0, 10, 0), line(10, 0, 15, 0),
+				line(15, 0, 15, 10), line(15, 10, 15, 15),
+				line(15, 15, 10, 15), line(10, 15, 0, 15),
+			}}},
+		},
+		{
+			name: "repeated implicit curves",
+			d:    "M0 0 c1 1 2 1 3 0 1 -1 2 -1 3 0",
+			want: []Subpath{{Start: Point{0, 0}, Segments: []Segment{
+				{Cmd: 'C', Start: Point{0, 0}, Ctrl: []Point{{1, 1}, {2, 1}}, End: Point{3, 0}},
+				{Cmd: 'C', Start: Point{3, 0}, Ctrl: []Point{{4, -1}, {5, -1}}, End: Point{6, 0}},
+			}}},
+		},
+		{
+			name: "smooth curves reflect the previous control point",
+			d:    "M0 0 C0 5 5 10 10 10 S20 5 20 0 Q25 -5 30 0 T40 0",
+			want: []Subpath{{Start: Point{0, 0}, Segments: []Segment{
+				{Cmd: 'C', Start: Point{0, 0}, Ctrl: []Point{{0, 5}, {5, 10}}, End: Point{10, 10}},
+				{Cmd: 'C', Start: Point{10, 10}, Ctrl: []Point{{15, 10}, {20, 5}}, End: Point{20, 0}},
+				{Cmd: 'Q', Start: Point{20, 0}, Ctrl: []Point{{25, -5}}, End: Point{30, 0}},
+				{Cmd: 'Q', Start: Point{30, 0}, Ctrl: []Point{{35, 5}}, End: Point{40, 0}},
+			}}},
+		},
+		{
+			name: "smooth curve without a previous curve uses the current point",
+			d:    "M0 0 S5 5 10 0",
+			want: []Subpath{{Start: Point{0, 0}, Segments: []Segment{
+				{Cmd: 'C', Start: Point{0, 0}, Ctrl: []Point{{0, 0}, {5, 5}}, End: Point{10, 0}},
+			}}},
+		},
+		{
+			name: "arcs",
+			d:    "M10 10 A5 5 0 0 1 20 10 a5 6 30 1 0 -10 0",
+			want: []Subpath{{Start: Point{10, 10}, Segments: []Segment{
+				{Cmd: 'A', Start: Point{10, 10}, End: Point{20, 10}, RX: 5, RY: 5, Sweep: true},
+				{Cmd: 'A', Start: Point{20, 10}, End: Point{10, 10}, RX: 5, RY: 6, Rotation: 30, LargeArc: true},
+			}}},
+		},
+		{
+			name: "arc flags packed against the end point",
+			d:    "M0 0a5 5 0 1110 0",
+			want: []Subpath{{Start: Point{0, 0}, Segments: []Segment{
+				{Cmd: 'A', Start: Point{0, 0}, End: Point{10, 0}, RX: 5, RY: 5, LargeArc: true, Sweep: true},
+			}}},
+		},
+		{
+			name: "exponents",
+			d:    "M1e1 2E-1 L1e-3,5e+1",
+			want: []Subpath{{Start: Point{10, 0.2}, Segments: []Segment{line(10, 0.2, 0.001, 50)}}},
+		},
+		{
+			name: "numbers packed without separators",
+			d:    "M.5.5L1-2-.5.25",
+			want: []Subpath{{Start: Point{0.5, 0.5}, Segments: []Segment{line(0.5, 0.5, 1, -2), line(1, -2, -0.5, 0.25)}}},
+		},
+		{
+			name: "drawing after closepath starts a new subpath at the closed start",
+			d:    "M0 0 h10 v10 z l5 5",
+			want: []Subpath{
+				{Start: Point{0, 0}, Closed: true, Segments: []Segment{line(0, 0, 10, 0), line(10, 0, 10, 10)}},
+				{Start: Point{0, 0}, Segments: []Segment{line(0, 0, 5, 5)}},
+			},
+		},
+		{
+			name: "relative moveto after closepath is from the closed start",
+			d:    "M10 10 h10 z m5 5 h1",
+			want: []Subpath{
+				{Start: Point{10, 10}, Closed: true, Segments: []Segment{line(10, 10, 20, 10)}},
+				{Start: Point{15, 15}, Segments: []Segment{line(15, 15, 16, 15)}},
+			},
+		},
+		{
+			name: "empty",
+			d:    " \n ",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.d)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.d, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q)\n got %+v\nwant %+v", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		d    string
+	}{
+		{"no moveto", "L10 10"},
+		{"number before any command", "10 10"},
+		{"missing coordinate", "M10"},
+		{"number after closepath", "M0 0 h10 z 5"},
+		{"bad arc flag", "M0 0 a5 5 0 2 0 10 0"},
+		{"missing arc flag", "M0 0 a5 5 0"},
+		{"lone sign", "M- 0"},
+		{"lone point", "M. 0"},
+		{"unknown command", "M0 0 X10 10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Parse(tt.d); err == nil {
+				t.Errorf("Parse(%q) = %+v, want an error", tt.d, got)
+			}
+		})
+	}
+}
+
+func TestSubpathNodes(t *testing.T) {
+	tests := []struct {
+		d    string
+		want int
+	}{
+		{"M0 0", 1},
+		{"M0 0 h10 v10", 3},
+		{"M0 0 h10 v10 z", 3},
+		// the final node sits on the first, so Inkscape shows them as one
+		{"M0 0 h10 v10 L0 0 z", 3},
+		{"M0 0 h10 v10 L0 0", 4},
+	}
+
+	for _, tt := range tests {
+		sps, err := Parse(tt.d)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.d, err)
+		}
+		if got := sps[0].Nodes(); got != tt.want {
+			t.Errorf("Nodes() of %q = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentZeroLength(t *testing.T) {
+	tests := []struct {
+		name string
+		seg  Segment
+		want bool
+	}{
+		{"line", line(0, 0, 1, 0), false},
+		{"line to the same point", line(1, 1, 1, 1), true},
+		{"line within NodeTol", line(1, 1, 1+NodeTol/2, 1), true},
+		{"curve with every point together", Segment{Cmd: 'C', Start: Point{1, 1}, Ctrl: []Point{{1, 1}, {1, 1}}, End: Point{1, 1}}, true},
+		{"curve that loops back to its start", Segment{Cmd: 'C', Start: Point{1, 1}, Ctrl: []Point{{5, 0}, {5, 5}}, End: Point{1, 1}}, false},
+		{"quadratic that goes out and back", Segment{Cmd: 'Q', Start: Point{1, 1}, Ctrl: []Point{{5, 5}}, End: Point{1, 1}}, false},
+		{"arc to the same point", Segment{Cmd: 'A', Start: Point{1, 1}, End: Point{1, 1}, RX: 5, RY: 5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.seg.ZeroLength(); got != tt.want {
+				t.Errorf("ZeroLength() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
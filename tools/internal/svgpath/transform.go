@@ -0,0 +1,128 @@
+package svgpath
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Matrix is an SVG transform matrix(A,B,C,D,E,F), mapping (x,y) to (Ax+Cy+E, Bx+Dy+F).
+type Matrix struct {
+	A, B, C, D, E, F float64
+}
+
+// Identity is the transform that leaves every point where it is.
+var Identity = Matrix{A: 1, D: 1}
+
+// Mul returns the transform that applies n, then m.
+func (m Matrix) Mul(n Matrix) Matrix {
+	return Matrix{
+		A: m.A*n.A + m.C*n.B,
+		B: m.B*n.A + m.D*n.B,
+		C: m.A*n.C + m.C*n.D,
+		D: m.B*n.C + m.D*n.D,
+		E: m.A*n.E + m.C*n.F + m.E,
+		F: m.B*n.E + m.D*n.F + m.F,
+	}
+}
+
+// Apply transforms p.
+func (m Matrix) Apply(p Point) Point {
+	return Point{m.A*p.X + m.C*p.Y + m.E, m.B*p.X + m.D*p.Y + m.F}
+}
+
+// Invert returns the transform that undoes m. It returns false if m collapses the plane to a line or point.
+func (m Matrix) Invert() (Matrix, bool) {
+	det := m.A*m.D - m.B*m.C
+	if det == 0 {
+		return Matrix{}, false
+	}
+	return Matrix{
+		A: m.D / det,
+		B: -m.B / det,
+		C: -m.C / det,
+		D: m.A / det,
+		E: (m.C*m.F - m.D*m.E) / det,
+		F: (m.B*m.E - m.A*m.F) / det,
+	}, true
+}
+
+// Scale returns the average factor by which the transform scales lengths.
+func (m Matrix) Scale() float64 {
+	return math.Sqrt(math.Abs(m.A*m.D - m.B*m.C))
+}
+
+// ParseTransform parses an SVG transform attribute into a single matrix.
+func ParseTransform(s string) (Matrix, error) {
+	m := Identity
+	p := &pathParser{s: s}
+
+	for {
+		p.skipSeparators()
+		if p.eof() {
+			return m, nil
+		}
+
+		start := p.i
+		for !p.eof() && p.s[p.i] != '(' {
+			p.i++
+		}
+		if p.eof() {
+			return Matrix{}, fmt.Errorf("expected '(' after %q", p.s[start:])
+		}
+		name := strings.TrimSpace(p.s[start:p.i])
+		p.i++ // '('
+
+		var args []float64
+		for {
+			p.skipSeparators()
+			if p.eof() {
+				return Matrix{}, fmt.Errorf("unterminated %s()", name)
+			}
+			if p.s[p.i] == ')' {
+				p.i++
+				break
+			}
+			v, err := p.number()
+			if err != nil {
+				return Matrix{}, fmt.Errorf("invalid %s(): %w", name, err)
+			}
+			args = append(args, v)
+		}
+
+		t, err := transformFunc(name, args)
+		if err != nil {
+			return Matrix{}, err
+		}
+		m = m.Mul(t)
+	}
+}
+
+func transformFunc(name string, args []float64) (Matrix, error) {
+	n := len(args)
+	switch {
+	case name == "matrix" && n == 6:
+		return Matrix{args[0], args[1], args[2], args[3], args[4], args[5]}, nil
+	case name == "translate" && n == 1:
+		return Matrix{A: 1, D: 1, E: args[0]}, nil
+	case name == "translate" && n == 2:
+		return Matrix{A: 1, D: 1, E: args[0], F: args[1]}, nil
+	case name == "scale" && n == 1:
+		return Matrix{A: args[0], D: args[0]}, nil
+	case name == "scale" && n == 2:
+		return Matrix{A: args[0], D: args[1]}, nil
+	case name == "rotate" && (n == 1 || n == 3):
+		a := args[0] * math.Pi / 180
+		r := Matrix{A: math.Cos(a), B: math.Sin(a), C: -math.Sin(a), D: math.Cos(a)}
+		if n == 3 {
+			// rotate about (cx,cy)
+			r = Matrix{A: 1, D: 1, E: args[1], F: args[2]}.Mul(r).Mul(Matrix{A: 1, D: 1, E: -args[1], F: -args[2]})
+		}
+		return r, nil
+	case name == "skewX" && n == 1:
+		return Matrix{A: 1, C: math.Tan(args[0] * math.Pi / 180), D: 1}, nil
+	case name == "skewY" && n == 1:
+		return Matrix{A: 1, B: math.Tan(args[0] * math.Pi / 180), D: 1}, nil
+	}
+	return Matrix{}, fmt.Errorf("unsupported transform %s() with %d arguments", name, n)
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image/png"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plane-watch/pw-silhouettes/internal/airframe"
+	"github.com/plane-watch/pw-silhouettes/internal/spritesheet"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+//go:embed preview.html
+var previewPage []byte
+
+// server rebuilds the spritesheet when its sources change, and serves the latest build.
+type server struct {
+	AirframesPath   string
+	SilhouettesPath string
+	Builder         *spritesheet.Builder
+
+	// buildLog is what rebuilds log to. Unless verbose, it leaves out the airframe and spritesheet
+	// packages' logging of every file, which is too much on every rebuild.
+	buildLog zerolog.Logger
+
+	// hosts are the host:ports the server is reached at, for the studio's DNS rebinding check
+	hosts map[string]bool
+
+	mu       sync.Mutex
+	json     []byte // the last good build
+	png      []byte
+	scales   []byte // where each of the last good build's scales came from, for the studio
+	status   status
+	changed  chan struct{} // closed, and replaced, whenever status changes
+	lastScan uint64
+}
+
+// built is a good build of the spritesheet.
+type built struct {
+	json, png []byte
+	scales    []byte   // a map of each canonical airframe to where its scale came from: explicit, derived or default
+	problems  []string // airframes left out
+}
+
+// status describes the last build, for the preview page.
+type status struct {
+	Build    int       `json:"build"` // how many good builds there have been
+	BuiltAt  time.Time `json:"builtAt"`
+	Error    string    `json:"error,omitempty"`    // why the last build failed, if it did
+	Problems []string  `json:"problems,omitempty"` // airframes left out of the last build
+}
+
+func runApp(ctx context.Context, cmd *cli.Command) error {
+	// an explicitly named scale curve must exist
+	curve, err := airframe.ScaleCurveFromFile(
+		airframe.ScaleCurveFile(cmd.String("scale_curve"), cmd.String("airframes_path")),
+		!cmd.IsSet("scale_curve"),
+	)
+	if err != nil {
+		return err
+	}
+
+	buildLog := log.Logger
+	if !cmd.Bool("verbose") {
+		buildLog = buildLog.Level(zerolog.WarnLevel)
+	}
+
+	s := &server{
+		AirframesPath:   cmd.String("airframes_path"),
+		SilhouettesPath: cmd.String("silhouettes_path"),
+		Builder: &spritesheet.Builder{
+			InkscapeBinary: cmd.String("inkscape_binary"),
+			ScaleCurve:     curve,
+			Logger:         &buildLog,
+		},
+		buildLog: buildLog,
+		changed:  make(chan struct{}),
+	}
+
+	ln, err := net.Listen("tcp", cmd.String("listen"))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	s.hosts = listenHosts(cmd.String("listen"), ln.Addr().(*net.TCPAddr))
+	log.Info().Str("url", "http://"+ln.Addr().String()+"/").Msg("serving preview")
+	log.Info().Str("url", "http://"+ln.Addr().String()+"/studio").Msg("serving studio")
+
+	go s.watch(ctx, cmd.Duration("interval"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePreview)
+	mux.HandleFunc("GET /spritesheet.json", s.handleJSON)
+	mux.HandleFunc("GET /spritesheet.png", s.handlePNG)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /studio", s.handleStudio)
+	mux.HandleFunc("GET /studio/scales", s.handleScales)
+	mux.HandleFunc("POST /studio/airframes/{designator}", s.handleSaveRender)
+	srv := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	return nil
+}
+
+// watch rebuilds the spritesheet whenever the airframes or silhouettes change.
+func (s *server) watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		scan, err := s.scan()
+		if err != nil {
+			s.setStatus(func(st *status) { st.Error = err.Error() })
+			log.Error().Err(err).Msg("failed to check for changes")
+		} else if scan != s.lastScan {
+			s.lastScan = scan
+			s.rebuild()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// scan returns a hash of the name, size and modification time of every airframe and silhouette.
+func (s *server) scan() (uint64, error) {
+	h := fnv.New64a()
+	for _, dir := range []string{s.AirframesPath, s.SilhouettesPath} {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !(strings.HasSuffix(path, ".json") || strings.HasSuffix(path, ".svg")) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00%d\x00", path, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to scan %s: %w", dir, err)
+		}
+	}
+	return h.Sum64(), nil
+}
+
+// rebuild builds the spritesheet, leaving out airframes whose artwork is missing. If the build
+// fails, the last good build is still served.
+func (s *server) rebuild() {
+	start := time.Now()
+
+	b, err := s.build()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to build spritesheet, still serving the last good build")
+		s.setStatus(func(st *status) { st.Error = err.Error() })
+		return
+	}
+	for _, p := range b.problems {
+		log.Warn().Msg(p)
+	}
+
+	s.mu.Lock()
+	s.json, s.png, s.scales = b.json, b.png, b.scales
+	s.mu.Unlock()
+	s.setStatus(func(st *status) {
+		st.Build++
+		st.BuiltAt = time.Now()
+		st.Error = ""
+		st.Problems = b.problems
+	})
+	log.Info().
+		Dur("took", time.Since(start)).
+		Int("problems", len(b.problems)).
+		Msg("rebuilt spritesheet")
+}
+
+func (s *server) build() (*built, error) {
+	all, err := airframe.FromDirLogger(s.AirframesPath, s.buildLog)
+	if err != nil {
+		return nil, err
+	}
+	b := new(built)
+
+	// leave out airframes with missing artwork, rather than failing the whole build
+	var airframes []*airframe.Airframe
+	for _, af := range all {
+		missing := ""
+		for _, frame := range af.Art.Frames {
+			if _, err := os.Stat(frame.Src); err != nil {
+				missing = frame.Src
+				break
+			}
+		}
+		if missing != "" {
+			b.problems = append(b.problems, fmt.Sprintf("%s left out, as %s is missing", af.ICAO.Designator, missing))
+			continue
+		}
+		airframes = append(airframes, af)
+	}
+
+	// the builder fills in the scales the files leave out, so note which ones they set first
+	scales := make(map[string]string)
+	for _, af := range airframes {
+		switch {
+		case af.AliasOf != nil:
+		case af.Render.Scale != 0:
+			scales[af.ICAO.Designator] = "explicit"
+		case s.Builder.ScaleCurve != nil && af.Size() > 0:
+			scales[af.ICAO.Designator] = "derived"
+		default:
+			scales[af.ICAO.Designator] = "default"
+		}
+	}
+
+	out, img, err := s.Builder.Build(airframes, "spritesheet.png")
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode spritesheet: %w", err)
+	}
+	b.png = buf.Bytes()
+	if b.json, err = json.MarshalIndent(out, "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to marshal spritesheet json: %w", err)
+	}
+	if b.scales, err = json.Marshal(scales); err != nil {
+		return nil, fmt.Errorf("failed to marshal scales: %w", err)
+	}
+	return b, nil
+}
+
+func (s *server) setStatus(update func(st *status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.status)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(previewPage)
+}
+
+func (s *server) handleJSON(w http.ResponseWriter, r *http.Request) {
+	s.serveBuild(w, "application/json", func() []byte { return s.json })
+}
+
+func (s *server) handlePNG(w http.ResponseWriter, r *http.Request) {
+	s.serveBuild(w, "image/png", func() []byte { return s.png })
+}
+
+// serveBuild serves part of the last good build, so that other apps, such as a local copy of
+// Plane Watch, can load it too.
+func (s *server) serveBuild(w http.ResponseWriter, contentType string, part func() []byte) {
+	s.mu.Lock()
+	b := part()
+	s.mu.Unlock()
+	if b == nil {
+		http.Error(w, "the spritesheet hasn't been built yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(b)
+}
+
+// handleEvents sends the status whenever it changes, as server-sent events.
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+
+	for {
+		s.mu.Lock()
+		b, err := json.Marshal(s.status)
+		changed := s.changed
+		s.mu.Unlock()
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:   "serve",
+	Usage:  "Rebuild the spritesheet whenever the airframes or silhouettes change, and serve it with a live preview, and a studio for setting anchors and scales",
+	Action: runApp,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "airframes_path",
+			Aliases: []string{"afp"},
+			Usage:   "Path to the airframes JSON directory",
+			Value:   "airframes/",
+		},
+		&cli.StringFlag{
+			Name:  "silhouettes_path",
+			Usage: "Path to the silhouettes SVG directory",
+			Value: "silhouettes/",
+		},
+		&cli.StringFlag{
+			Name:     "inkscape_binary",
+			Aliases:  []string{"inkscape"},
+			Usage:    "Path to the inkscape v1+ binary",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "scale_curve",
+			Usage: "Path to the scale curve used to derive render.scale from wingspan_m and length_m (default: render_scale.json beside the airframes directory, skipped with a warning if it doesn't exist)",
+		},
+		&cli.StringFlag{
+			Name:  "listen",
+			Usage: "Address to serve on",
+			Value: "localhost:8080",
+		},
+		&cli.DurationFlag{
+			Name:  "interval",
+			Usage: "How often to check for changes",
+			Value: 500 * time.Millisecond,
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Log every airframe and sprite on each rebuild",
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
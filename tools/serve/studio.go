@@ -0,0 +1,180 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/airframe"
+	"github.com/plane-watch/pw-silhouettes/internal/jsonedit"
+	"github.com/plane-watch/pw-silhouettes/internal/spritesheet"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed studio.html
+var studioPage []byte
+
+// renderEdit is the studio's change to an airframe's render hints.
+type renderEdit struct {
+	Anchor *airframe.Anchor `json:"anchor"`
+	Scale  *float64         `json:"scale"`
+}
+
+func (s *server) handleStudio(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(studioPage)
+}
+
+// handleScales serves where each airframe's scale came from in the last good build, so the studio
+// can tell an explicit render.scale from one derived from the airframe's size.
+func (s *server) handleScales(w http.ResponseWriter, r *http.Request) {
+	s.serveBuild(w, "application/json", func() []byte { return s.scales })
+}
+
+// handleSaveRender writes the studio's anchor and scale back to the airframe's JSON file. The
+// watcher then picks up the change and rebuilds the spritesheet.
+func (s *server) handleSaveRender(w http.ResponseWriter, r *http.Request) {
+	// a page from another site could rebind its name to this address, so the request must be for
+	// this server by name too
+	if !s.knownHost(r.Host) {
+		http.Error(w, "unexpected host "+r.Host, http.StatusForbidden)
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme != "http" || !s.knownHost(u.Host) {
+			http.Error(w, "unexpected origin "+origin, http.StatusForbidden)
+			return
+		}
+	}
+	// only accepting JSON means a cross-site page can't post here without a preflight, which isn't answered
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+		http.Error(w, "expected application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	var edit renderEdit
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&edit); err != nil {
+		http.Error(w, "invalid edit: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := edit.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	designator := strings.ToUpper(r.PathValue("designator"))
+	path, err := s.airframeFile(designator)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err := writeRenderEdit(path, edit); err != nil {
+		log.Error().Err(err).Str("file", path).Msg("failed to save render hints")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Info().Str("airframe", designator).Str("file", path).Msg("saved render hints")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listenHosts returns the host:ports that the server listening on addr, as given by listen, can be
+// reached at. If it's listening on loopback, or every address, that's any of the loopback names.
+func listenHosts(listen string, addr *net.TCPAddr) map[string]bool {
+	port := strconv.Itoa(addr.Port)
+	hosts := map[string]bool{
+		listen:        true,
+		addr.String(): true,
+	}
+	if addr.IP.IsLoopback() || addr.IP.IsUnspecified() {
+		for _, h := range []string{"localhost", "127.0.0.1", "::1"} {
+			hosts[net.JoinHostPort(h, port)] = true
+		}
+	}
+	return hosts
+}
+
+// knownHost returns whether host, from a Host or Origin header, is one the server is reached at.
+func (s *server) knownHost(host string) bool {
+	// the port is left out when it's the default
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "80")
+	}
+	return s.hosts[host]
+}
+
+func (e renderEdit) validate() error {
+	// the anchor is within the artwork, which is 1px in from each edge of the sprite
+	size := spritesheet.SpriteWidth - 2
+	if e.Anchor != nil && (e.Anchor.X < 0 || e.Anchor.Y < 0 || e.Anchor.X >= size || e.Anchor.Y >= size) {
+		return fmt.Errorf("anchor %d,%d is outside the %dx%d artwork", e.Anchor.X, e.Anchor.Y, size, size)
+	}
+	if e.Scale != nil && !(*e.Scale >= 0.01 && *e.Scale <= 10) {
+		return fmt.Errorf("scale %v must be between 0.01 and 10", *e.Scale)
+	}
+	return nil
+}
+
+// airframeFile finds the JSON file for the canonical airframe with designator.
+func (s *server) airframeFile(designator string) (string, error) {
+	listing, err := os.ReadDir(s.AirframesPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read dir: %w", err)
+	}
+	for _, entry := range listing {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(s.AirframesPath, entry.Name())
+		af, err := airframe.FromFile(path)
+		if err != nil || af.ICAO.Designator != designator {
+			continue
+		}
+		if af.AliasOf != nil {
+			return "", fmt.Errorf("%s is an alias of %s, edit %s instead", designator, *af.AliasOf, *af.AliasOf)
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("no airframe %s in %s", designator, s.AirframesPath)
+}
+
+// writeRenderEdit changes just the edited values in the file, so the rest of it keeps its formatting.
+func writeRenderEdit(path string, edit renderEdit) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read airframe: %w", err)
+	}
+	if edit.Anchor != nil {
+		if data, err = jsonedit.Set(data, edit.Anchor.X, "render", "anchor", "x"); err != nil {
+			return fmt.Errorf("failed to set anchor: %w", err)
+		}
+		if data, err = jsonedit.Set(data, edit.Anchor.Y, "render", "anchor", "y"); err != nil {
+			return fmt.Errorf("failed to set anchor: %w", err)
+		}
+	}
+	if edit.Scale != nil {
+		// the slider's steps aren't exact in binary, eg: 1.1500000000000001
+		scale := math.Round(*edit.Scale*100) / 100
+		if data, err = jsonedit.Set(data, scale, "render", "scale"); err != nil {
+			return fmt.Errorf("failed to set scale: %w", err)
+		}
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("edited airframe is not valid JSON")
+	}
+	if err := svgdoc.WriteFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write airframe: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/raster"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var changelogCmd = &cli.Command{
+	Name:      "changelog",
+	Usage:     "Write a markdown changelog of the aircraft that changed between two spritesheet builds",
+	ArgsUsage: "old.json old.png new.json new.png",
+	Action:    runChangelog,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "output_md",
+			Usage: "Path to write the changelog to",
+			Value: "changelog.md",
+		},
+		&cli.StringFlag{
+			Name:  "thumbnail_dir",
+			Usage: "If set, write thumbnails of new and changed sprites to this directory, and show them in the changelog",
+		},
+		&cli.StringFlag{
+			Name:  "thumbnail_url",
+			Usage: "URL the thumbnails will be published at, such as the release's download URL (default: their path in --thumbnail_dir)",
+		},
+		&cli.IntFlag{
+			Name:  "tolerance",
+			Usage: "A pixel has changed if any channel differs by more than this, from 0 to 255",
+			Value: 8,
+		},
+		&cli.IntFlag{
+			Name:  "threshold",
+			Usage: "A sprite's artwork has changed if more than this many of its pixels changed",
+			Value: 0,
+		},
+		&cli.IntFlag{
+			Name:  "zoom",
+			Usage: "Draw the thumbnails at this multiple of the sprite size",
+			Value: 1,
+		},
+	},
+}
+
+// thumbnails writes images for the changelog, and returns where each will be found.
+type thumbnails struct {
+	Dir  string
+	URL  string
+	Zoom int
+}
+
+// Write writes img as name, and returns markdown showing it, or "" if thumbnails aren't wanted.
+func (t *thumbnails) Write(name, alt string, img *image.NRGBA) (string, error) {
+	if t.Dir == "" {
+		return "", nil
+	}
+	if err := raster.WritePNG(filepath.Join(t.Dir, name), img); err != nil {
+		return "", err
+	}
+	src := filepath.ToSlash(filepath.Join(t.Dir, name))
+	if t.URL != "" {
+		src = strings.TrimSuffix(t.URL, "/") + "/" + name
+	}
+	return fmt.Sprintf("![%s](%s)", alt, src), nil
+}
+
+func runChangelog(_ context.Context, cmd *cli.Command) error {
+	args := cmd.Args().Slice()
+	if len(args) != 4 {
+		return fmt.Errorf("expected 4 arguments (%s), got %d", cmd.ArgsUsage, len(args))
+	}
+	prev, err := readSheet(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	next, err := readSheet(args[2], args[3])
+	if err != nil {
+		return err
+	}
+	thumbs := &thumbnails{
+		Dir:  cmd.String("thumbnail_dir"),
+		URL:  cmd.String("thumbnail_url"),
+		Zoom: int(cmd.Int("zoom")),
+	}
+	if thumbs.Zoom < 1 {
+		return fmt.Errorf("zoom must be >= 1")
+	}
+	if thumbs.Dir != "" {
+		if err := os.MkdirAll(thumbs.Dir, 0755); err != nil {
+			return fmt.Errorf("failed to create thumbnail dir: %w", err)
+		}
+	}
+
+	art, err := compareArtwork(prev, next, int(cmd.Int("tolerance")), int(cmd.Int("threshold")))
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if err := writeChangelog(&b, prev, next, art, thumbs); err != nil {
+		return err
+	}
+	if err := os.WriteFile(cmd.String("output_md"), []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	log.Info().
+		Str("file", cmd.String("output_md")).
+		Msg("wrote changelog")
+	return nil
+}
+
+// writeChangelog writes a markdown section for each kind of change to the aircraft, suitable for
+// release notes.
+func writeChangelog(w io.Writer, prev, next *sheet, art []*artChange, thumbs *thumbnails) error {
+	d := compare(prev.JSON, next.JSON)
+
+	// removed types and changed aliases have their own sections
+	var broken []incompatibility
+	for _, c := range incompatibilities(prev, next) {
+		if c.Change == changeRenumbered || c.Change == changeSheetWidth {
+			broken = append(broken, c)
+		}
+	}
+
+	if d.Empty() && len(art) == 0 && len(broken) == 0 {
+		fmt.Fprintln(w, "No changes to the aircraft in the spritesheet.")
+		return nil
+	}
+
+	// aliases of each sprite, in the new spritesheet
+	aliases := map[string][]string{}
+	for des, name := range next.JSON.AirframeToSprite {
+		if des != name {
+			aliases[name] = append(aliases[name], des)
+		}
+	}
+
+	var airframes, newAliases []string
+	for _, des := range d.Added {
+		if next.JSON.AirframeToSprite[des] == des {
+			airframes = append(airframes, des)
+		} else {
+			newAliases = append(newAliases, des)
+		}
+	}
+
+	if len(airframes) > 0 {
+		fmt.Fprintf(w, "## ✈️ New airframes\n\n")
+		fmt.Fprintf(w, "| | Designator | Aliases |\n|---|---|---|\n")
+		for _, des := range airframes {
+			s, ok := next.JSON.Sprites[des]
+			thumb := ""
+			if ok && len(s.IDs) > 0 {
+				cell, err := next.JSON.Cell(next.PNG, s.IDs[0])
+				if err != nil {
+					return fmt.Errorf("failed to read %s from the new spritesheet: %w", des, err)
+				}
+				thumb, err = thumbs.Write("thumbnail-"+des+".png", des, scaled(cell, thumbs.Zoom))
+				if err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(w, "| %s | `%s` | %s |\n", thumb, des, code(aliases[des]))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(newAliases) > 0 || len(d.Aliases) > 0 {
+		fmt.Fprintf(w, "## 🔗 New aliases\n\n")
+		for _, des := range newAliases {
+			fmt.Fprintf(w, "- `%s` is drawn as `%s`\n", des, next.JSON.AirframeToSprite[des])
+		}
+		for _, c := range d.Aliases {
+			fmt.Fprintf(w, "- `%s` is now drawn as `%s` (was `%s`)\n", c.Designator, c.New, c.Old)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(art) > 0 {
+		fmt.Fprintf(w, "## 🎨 Changed artwork\n\n")
+		if thumbs.Dir != "" {
+			fmt.Fprintf(w, "Before, after, and the changed pixels in red.\n\n")
+		}
+		fmt.Fprintf(w, "| Sprite | Frame | Pixels changed | |\n|---|---|---|---|\n")
+		for _, c := range art {
+			thumb, err := thumbs.Write(fmt.Sprintf("thumbnail-%s-%d.png", c.Name, c.Frame+1),
+				fmt.Sprintf("%s frame %d", c.Name, c.Frame+1), composite([]*artChange{c}, thumbs.Zoom))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "| `%s` | %d | %d | %s |\n", c.Name, c.Frame+1, c.Pixels, thumb)
+		}
+		fmt.Fprintln(w)
+	}
+
+	var hints []string
+	for _, c := range d.Sprites {
+		for _, f := range c.Fields() {
+			// IDs aren't a render hint, and renumbering is listed with the other breaking changes
+			if f.Name != "ids" {
+				hints = append(hints, fmt.Sprintf("| `%s` | `%s` | `%s` | `%s` |", c.Name, f.Name, f.Old, f.New))
+			}
+		}
+	}
+	if len(hints) > 0 {
+		fmt.Fprintf(w, "## 📐 Changed render hints\n\n")
+		fmt.Fprintf(w, "| Sprite | Hint | Was | Now |\n|---|---|---|---|\n")
+		fmt.Fprintln(w, strings.Join(hints, "\n"))
+		fmt.Fprintln(w)
+	}
+
+	if len(d.Removed) > 0 {
+		fmt.Fprintf(w, "## 🗑️ Removed types\n\n")
+		for _, des := range d.Removed {
+			if name := prev.JSON.AirframeToSprite[des]; name != des {
+				fmt.Fprintf(w, "- `%s` (was drawn as `%s`)\n", des, name)
+			} else {
+				fmt.Fprintf(w, "- `%s`\n", des)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(broken) > 0 {
+		fmt.Fprintf(w, "## ⚠️ Breaking changes\n\n")
+		fmt.Fprintf(w, "Consumers that cache sprite IDs must be updated, as must those relying on removed types or changed aliases.\n\n")
+		for _, c := range broken {
+			if c.Designator != "" {
+				fmt.Fprintf(w, "- `%s`: %s\n", c.Designator, c.Detail)
+			} else {
+				fmt.Fprintf(w, "- %s\n", c.Detail)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// code formats items as a comma separated list of inline code, in order.
+func code(items []string) string {
+	items = slices.Sorted(slices.Values(items))
+	for i, s := range items {
+		items[i] = "`" + s + "`"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var compatCmd = &cli.Command{
+	Name:      "compat-check",
+	Usage:     "Fail if a new spritesheet changes anything consumers of the previous one rely on, unless allowed",
+	ArgsUsage: "old.json old.png new.json new.png",
+	Action:    runCompatCheck,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "allowlist",
+			Usage: "Path to the file listing the incompatible changes that are allowed",
+			Value: "spritesheet_compat.json",
+		},
+	},
+}
+
+// The incompatible changes.
+const (
+	changeRemoved    = "removed"     // a designator is no longer in the spritesheet
+	changeRenumbered = "renumbered"  // a sprite's existing frames have different IDs
+	changeRetargeted = "retargeted"  // a designator is drawn with a different sprite
+	changeSheetWidth = "sheet-width" // the spritesheet is a different width, which moves every sprite
+)
+
+var changes = []string{changeRemoved, changeRenumbered, changeRetargeted, changeSheetWidth}
+
+type (
+	// Allowlist is the compat-check allowlist file.
+	Allowlist struct {
+		Allow []Allowed `json:"allow"`
+	}
+
+	// Allowed allows a change to the designators matching a glob pattern. Designator is ignored
+	// for changes to the whole spritesheet.
+	Allowed struct {
+		Change     string `json:"change"`
+		Designator string `json:"designator"`
+		Reason     string `json:"reason"`
+	}
+
+	// incompatibility is a change that may break consumers of the old spritesheet.
+	incompatibility struct {
+		Change     string
+		Designator string
+		Detail     string
+	}
+)
+
+// loadAllowlist reads an allowlist file. If the file does not exist and missingOK is set, an empty
+// allowlist is returned.
+func loadAllowlist(filename string, missingOK bool) (*Allowlist, error) {
+	b, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) && missingOK {
+		return &Allowlist{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read allowlist: %w", err)
+	}
+	a := new(Allowlist)
+	if err := json.Unmarshal(b, a); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal allowlist: %w", err)
+	}
+	for _, e := range a.Allow {
+		if !slices.Contains(changes, e.Change) {
+			return nil, fmt.Errorf("allowlist entry has unknown change %q", e.Change)
+		}
+		if e.Change != changeSheetWidth && e.Designator == "" {
+			return nil, fmt.Errorf("allowlist entry for %q has no designator", e.Change)
+		}
+		if _, err := path.Match(e.Designator, ""); err != nil {
+			return nil, fmt.Errorf("invalid designator pattern %q: %w", e.Designator, err)
+		}
+	}
+	return a, nil
+}
+
+// allows returns the index of the first entry that allows the change, or -1.
+func (a *Allowlist) allows(c incompatibility) int {
+	for i, e := range a.Allow {
+		if e.Change != c.Change {
+			continue
+		}
+		if c.Change == changeSheetWidth {
+			return i
+		}
+		if ok, _ := path.Match(e.Designator, c.Designator); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+func runCompatCheck(_ context.Context, cmd *cli.Command) error {
+	args := cmd.Args().Slice()
+	if len(args) != 4 {
+		return fmt.Errorf("expected 4 arguments (%s), got %d", cmd.ArgsUsage, len(args))
+	}
+	prev, err := readSheet(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	next, err := readSheet(args[2], args[3])
+	if err != nil {
+		return err
+	}
+	// an explicitly named allowlist must exist
+	allowlist, err := loadAllowlist(cmd.String("allowlist"), !cmd.IsSet("allowlist"))
+	if err != nil {
+		return err
+	}
+
+	used := make([]bool, len(allowlist.Allow))
+	failed := 0
+	for _, c := range incompatibilities(prev, next) {
+		if i := allowlist.allows(c); i >= 0 {
+			used[i] = true
+			log.Info().
+				Str("change", c.Change).
+				Str("designator", c.Designator).
+				Str("reason", allowlist.Allow[i].Reason).
+				Msg(c.Detail + " (allowed)")
+			continue
+		}
+		failed++
+		log.Error().
+			Str("change", c.Change).
+			Str("designator", c.Designator).
+			Msg(c.Detail)
+	}
+
+	for i, e := range allowlist.Allow {
+		if !used[i] {
+			log.Warn().
+				Str("change", e.Change).
+				Str("designator", e.Designator).
+				Msg("allowlist entry matched no change, and can be removed")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d incompatible changes to the spritesheet (add them to %s if they're intended)", failed, cmd.String("allowlist"))
+	}
+	log.Info().Msg("the new spritesheet is compatible with the old")
+	return nil
+}
+
+// incompatibilities finds the changes from prev to next that may break consumers of prev.
+func incompatibilities(prev, next *sheet) []incompatibility {
+	var out []incompatibility
+	d := compare(prev.JSON, next.JSON)
+
+	if p, n := prev.PNG.Rect.Dx(), next.PNG.Rect.Dx(); p != n {
+		out = append(out, incompatibility{
+			Change: changeSheetWidth,
+			Detail: fmt.Sprintf("spritesheet width changed from %dpx to %dpx", p, n),
+		})
+	}
+	for _, des := range d.Removed {
+		out = append(out, incompatibility{
+			Change:     changeRemoved,
+			Designator: des,
+			Detail:     fmt.Sprintf("%s was removed", target(prev.JSON, des)),
+		})
+	}
+	for _, c := range d.Sprites {
+		if c.Renumbered() {
+			out = append(out, incompatibility{
+				Change:     changeRenumbered,
+				Designator: c.Name,
+				Detail:     fmt.Sprintf("sprite ids changed from %v to %v", c.Old.IDs, c.New.IDs),
+			})
+		}
+	}
+	for _, c := range d.Aliases {
+		out = append(out, incompatibility{
+			Change:     changeRetargeted,
+			Designator: c.Designator,
+			Detail:     fmt.Sprintf("drawn with sprite %s instead of %s", c.New, c.Old),
+		})
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/plane-watch/pw-silhouettes/internal/spritesheet"
+)
+
+type (
+	// delta is what changed in the JSON between two spritesheets.
+	delta struct {
+		Added   []string // designators only in the new spritesheet
+		Removed []string // designators only in the old spritesheet
+		Aliases []aliasChange
+		Sprites []spriteChange
+	}
+
+	// aliasChange is a designator that's drawn with a different sprite
+	aliasChange struct {
+		Designator string
+		Old, New   string
+	}
+
+	// spriteChange is a sprite in both spritesheets whose details differ
+	spriteChange struct {
+		Name     string
+		Old, New spritesheet.Sprite
+	}
+
+	// fieldChange is a detail of a sprite that changed, formatted as it is in the JSON
+	fieldChange struct {
+		Name     string
+		Old, New string
+	}
+)
+
+// compare finds what changed in the JSON from prev to next.
+func compare(prev, next *spritesheet.Output) *delta {
+	d := &delta{}
+	for _, des := range slices.Sorted(maps.Keys(next.AirframeToSprite)) {
+		p, ok := prev.AirframeToSprite[des]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, des)
+		case p != next.AirframeToSprite[des]:
+			d.Aliases = append(d.Aliases, aliasChange{Designator: des, Old: p, New: next.AirframeToSprite[des]})
+		}
+	}
+	for _, des := range slices.Sorted(maps.Keys(prev.AirframeToSprite)) {
+		if _, ok := next.AirframeToSprite[des]; !ok {
+			d.Removed = append(d.Removed, des)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(next.Sprites)) {
+		p, ok := prev.Sprites[name]
+		if !ok {
+			continue
+		}
+		c := spriteChange{Name: name, Old: p, New: next.Sprites[name]}
+		if len(c.Fields()) > 0 {
+			d.Sprites = append(d.Sprites, c)
+		}
+	}
+	return d
+}
+
+// Empty reports whether nothing changed.
+func (d *delta) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Aliases) == 0 && len(d.Sprites) == 0
+}
+
+// Renumbered reports whether any of the sprite's existing frames moved to a different ID. Frames
+// added to the end don't renumber the others.
+func (c spriteChange) Renumbered() bool {
+	return len(c.New.IDs) < len(c.Old.IDs) || !slices.Equal(c.Old.IDs, c.New.IDs[:len(c.Old.IDs)])
+}
+
+// Fields returns each detail of the sprite that changed.
+func (c spriteChange) Fields() []fieldChange {
+	var out []fieldChange
+	field := func(name string, prev, next any) {
+		if p, n := fmt.Sprint(prev), fmt.Sprint(next); p != n {
+			out = append(out, fieldChange{Name: name, Old: p, New: n})
+		}
+	}
+	field("ids", c.Old.IDs, c.New.IDs)
+	field("anchor", anchor(c.Old), anchor(c.New))
+	field("scale", c.Old.Scale, c.New.Scale)
+	field("noRotate", c.Old.NoRotate, c.New.NoRotate)
+	field("frameTime", frameTime(c.Old), frameTime(c.New))
+	field("metresPerPixel", c.Old.MetresPerPixel, c.New.MetresPerPixel)
+	return out
+}
+
+// String describes the change as "name old → new".
+func (f fieldChange) String() string {
+	return fmt.Sprintf("%s %s → %s", f.Name, f.Old, f.New)
+}
+
+func anchor(s spritesheet.Sprite) string {
+	return fmt.Sprintf("%d,%d", s.Anchor.X, s.Anchor.Y)
+}
+
+func frameTime(s spritesheet.Sprite) string {
+	if s.FrameTime == nil {
+		return "null"
+	}
+	return fmt.Sprint(*s.FrameTime)
+}
+
+// target describes what a designator is drawn with: its own sprite, or an alias of another's.
+func target(out *spritesheet.Output, designator string) string {
+	name := out.AirframeToSprite[designator]
+	if name == designator {
+		return designator
+	}
+	return fmt.Sprintf("%s → %s (alias)", designator, name)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/plane-watch/pw-silhouettes/internal/raster"
+	"github.com/plane-watch/pw-silhouettes/internal/spritesheet"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var diffCmd = &cli.Command{
+	Name:      "diff",
+	Usage:     "Report what changed between two spritesheet builds, and show the sprites whose artwork changed",
+	ArgsUsage: "old.json old.png new.json new.png",
+	Action:    runDiff,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "output_png",
+			Usage: "Path to write the before/after/difference image of the changed sprites to",
+			Value: "spritesheet-diff.png",
+		},
+		&cli.IntFlag{
+			Name:  "tolerance",
+			Usage: "A pixel has changed if any channel differs by more than this, from 0 to 255",
+			Value: 8,
+		},
+		&cli.IntFlag{
+			Name:  "threshold",
+			Usage: "A sprite's artwork has changed if more than this many of its pixels changed",
+			Value: 0,
+		},
+		&cli.IntFlag{
+			Name:  "zoom",
+			Usage: "Draw the sprites in the difference image at this multiple of their size",
+			Value: 2,
+		},
+	},
+}
+
+const (
+	// gap is the space in px around each sprite in the difference image
+	gap = 8
+)
+
+// sheet is a built spritesheet.
+type sheet struct {
+	JSON *spritesheet.Output
+	PNG  *image.NRGBA
+}
+
+// artChange is a frame of a sprite whose pixels changed.
+type artChange struct {
+	Name          string
+	Frame         int // 0-based
+	OldID, NewID  int
+	Pixels        int // how many pixels changed
+	Before, After *image.NRGBA
+	Changed       *raster.Mask
+}
+
+func runDiff(_ context.Context, cmd *cli.Command) error {
+	args := cmd.Args().Slice()
+	if len(args) != 4 {
+		return fmt.Errorf("expected 4 arguments (%s), got %d", cmd.ArgsUsage, len(args))
+	}
+	prev, err := readSheet(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	next, err := readSheet(args[2], args[3])
+	if err != nil {
+		return err
+	}
+	zoom := int(cmd.Int("zoom"))
+	if zoom < 1 {
+		return fmt.Errorf("zoom must be >= 1")
+	}
+
+	d := compare(prev.JSON, next.JSON)
+	art, err := compareArtwork(prev, next, int(cmd.Int("tolerance")), int(cmd.Int("threshold")))
+	if err != nil {
+		return err
+	}
+
+	writeReport(os.Stdout, prev, next, d, art)
+
+	if len(art) > 0 {
+		if err := raster.WritePNG(cmd.String("output_png"), composite(art, zoom)); err != nil {
+			return err
+		}
+		log.Info().
+			Int("sprites", len(art)).
+			Str("file", cmd.String("output_png")).
+			Msg("wrote changed sprites")
+	}
+	return nil
+}
+
+func readSheet(jsonFile, pngFile string) (*sheet, error) {
+	j, err := spritesheet.FromFile(jsonFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", jsonFile, err)
+	}
+	p, err := spritesheet.ReadPNG(pngFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", pngFile, err)
+	}
+	return &sheet{JSON: j, PNG: p}, nil
+}
+
+// compareArtwork compares the frames of each sprite in both spritesheets, by name and frame
+// number rather than ID, so renumbered sprites are compared with themselves.
+func compareArtwork(prev, next *sheet, tolerance, threshold int) ([]*artChange, error) {
+	var out []*artChange
+	for _, name := range slices.Sorted(maps.Keys(next.JSON.Sprites)) {
+		p, ok := prev.JSON.Sprites[name]
+		if !ok {
+			continue
+		}
+		n := next.JSON.Sprites[name]
+		for i := range min(len(p.IDs), len(n.IDs)) {
+			before, err := prev.JSON.Cell(prev.PNG, p.IDs[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s frame %d from the old spritesheet: %w", name, i+1, err)
+			}
+			after, err := next.JSON.Cell(next.PNG, n.IDs[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s frame %d from the new spritesheet: %w", name, i+1, err)
+			}
+			if before.Rect != after.Rect {
+				// the sprite size changed, which the report already shows
+				continue
+			}
+			changed := changedPixels(before, after, tolerance)
+			if count := changed.Count(); count > threshold {
+				out = append(out, &artChange{
+					Name:    name,
+					Frame:   i,
+					OldID:   p.IDs[i],
+					NewID:   n.IDs[i],
+					Pixels:  count,
+					Before:  before,
+					After:   after,
+					Changed: changed,
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+// changedPixels returns the pixels that look different in a and b. Colours are compared
+// premultiplied by their alpha, so fully transparent pixels are all the same.
+func changedPixels(a, b *image.NRGBA, tolerance int) *raster.Mask {
+	m := raster.NewMask(a.Rect.Dx(), a.Rect.Dy())
+	for y := 0; y < m.H; y++ {
+		for x := 0; x < m.W; x++ {
+			ca := color.RGBAModel.Convert(a.NRGBAAt(x, y)).(color.RGBA)
+			cb := color.RGBAModel.Convert(b.NRGBAAt(x, y)).(color.RGBA)
+			d := max(absDiff(ca.R, cb.R), absDiff(ca.G, cb.G), absDiff(ca.B, cb.B), absDiff(ca.A, cb.A))
+			m.On[y*m.W+x] = d > tolerance
+		}
+	}
+	return m
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// writeReport writes the changes as text, one line per change, under a heading for each kind of change.
+func writeReport(w io.Writer, prev, next *sheet, d *delta, art []*artChange) {
+	changed := false
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		changed = true
+		fmt.Fprintf(w, "%s (%d):\n", title, len(lines))
+		for _, l := range lines {
+			fmt.Fprintf(w, "  %s\n", l)
+		}
+	}
+
+	var lines []string
+	if p, n := prev.PNG.Rect.Size(), next.PNG.Rect.Size(); p != n {
+		lines = append(lines, fmt.Sprintf("size %dx%d → %dx%d", p.X, p.Y, n.X, n.Y))
+	}
+	pm, nm := prev.JSON.Metadata, next.JSON.Metadata
+	if pm.SpriteWidth != nm.SpriteWidth || pm.SpriteHeight != nm.SpriteHeight {
+		lines = append(lines, fmt.Sprintf("sprite size %dx%d → %dx%d", pm.SpriteWidth, pm.SpriteHeight, nm.SpriteWidth, nm.SpriteHeight))
+	}
+	if prev.JSON.Version != next.JSON.Version {
+		lines = append(lines, fmt.Sprintf("version %d → %d", prev.JSON.Version, next.JSON.Version))
+	}
+	section("changed spritesheet", lines)
+
+	lines = nil
+	for _, des := range d.Added {
+		lines = append(lines, "+ "+target(next.JSON, des))
+	}
+	section("added designators", lines)
+
+	lines = nil
+	for _, des := range d.Removed {
+		lines = append(lines, "- "+target(prev.JSON, des))
+	}
+	section("removed designators", lines)
+
+	lines = nil
+	for _, c := range d.Aliases {
+		lines = append(lines, fmt.Sprintf("~ %s: drawn with %s → %s", c.Designator, c.Old, c.New))
+	}
+	section("changed aliases", lines)
+
+	lines = nil
+	for _, c := range d.Sprites {
+		for _, f := range c.Fields() {
+			lines = append(lines, fmt.Sprintf("~ %s: %s", c.Name, f.String()))
+		}
+	}
+	section("changed sprites", lines)
+
+	lines = nil
+	for i, c := range art {
+		lines = append(lines, fmt.Sprintf("~ %s frame %d (sprite %d → %d): %d px changed, row %d of the difference image",
+			c.Name, c.Frame+1, c.OldID, c.NewID, c.Pixels, i+1))
+	}
+	section("changed artwork", lines)
+
+	if !changed {
+		fmt.Fprintln(w, "no changes")
+	}
+}
+
+// composite draws a row for each changed frame: before, after, and the difference, which shows the
+// changed pixels in red over a faded copy of the new sprite.
+func composite(art []*artChange, zoom int) *image.NRGBA {
+	w, h := art[0].After.Rect.Dx()*zoom, art[0].After.Rect.Dy()*zoom
+	img := image.NewNRGBA(image.Rect(0, 0, 3*w+4*gap, len(art)*(h+gap)+gap))
+	draw.Draw(img, img.Rect, image.White, image.Point{}, draw.Src)
+
+	panel := color.NRGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff}
+	for row, c := range art {
+		y := gap + row*(h+gap)
+		for col, src := range []*image.NRGBA{c.Before, c.After, difference(c)} {
+			r := image.Rect(0, 0, w, h).Add(image.Pt(gap+col*(w+gap), y))
+			draw.Draw(img, r, &image.Uniform{C: panel}, image.Point{}, draw.Src)
+			draw.Draw(img, r, scaled(src, zoom), image.Point{}, draw.Over)
+		}
+	}
+	return img
+}
+
+func difference(c *artChange) *image.NRGBA {
+	img := image.NewNRGBA(c.After.Rect)
+	for y := 0; y < c.Changed.H; y++ {
+		for x := 0; x < c.Changed.W; x++ {
+			if c.Changed.At(x, y) {
+				img.SetNRGBA(x, y, color.NRGBA{R: 0xff, A: 0xff})
+				continue
+			}
+			a := c.After.NRGBAAt(x, y)
+			a.A /= 3
+			img.SetNRGBA(x, y, a)
+		}
+	}
+	return img
+}
+
+// scaled enlarges img by a whole number, without smoothing.
+func scaled(img *image.NRGBA, zoom int) *image.NRGBA {
+	out := image.NewNRGBA(image.Rect(0, 0, img.Rect.Dx()*zoom, img.Rect.Dy()*zoom))
+	for y := 0; y < out.Rect.Dy(); y++ {
+		for x := 0; x < out.Rect.Dx(); x++ {
+			out.SetNRGBA(x, y, img.NRGBAAt(img.Rect.Min.X+x/zoom, img.Rect.Min.Y+y/zoom))
+		}
+	}
+	return out
+}
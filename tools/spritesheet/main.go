@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:  "spritesheet",
+	Usage: "Compare built spritesheets",
+	Commands: []*cli.Command{
+		diffCmd,
+		compatCmd,
+		changelogCmd,
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
 	"fmt"
@@ -10,6 +11,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v3"
 )
@@ -17,57 +20,112 @@ import (
 const (
 	svgNS      = "http://www.w3.org/2000/svg"
 	inkscapeNS = "http://www.inkscape.org/namespaces/inkscape"
+	sodipodiNS = "http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd"
 
-	wantSizePx = 70.0
-
-	wantFill         = "#ffffff"
-	wantStroke       = "#000000"
-	wantStrokeWidth  = 0.26458333
-	wantOpacity      = 1.0
-	defaultStrokeTol = 0.0005 // "close enough" tolerance for stroke-width
-	defaultSizeTolPx = 0.01
+	wantOpacity = 1.0
 )
 
 type Issue struct {
 	File string
 	Line int
+	Rule string
 	Msg  string
+
+	// Severity is set from the profile once validation is complete.
+	// Warnings are reported but only fail validation in strict mode.
+	Severity svgcheck.Severity
 }
 
 func runApp(_ context.Context, cmd *cli.Command) error {
 
 	var issues []Issue
 
-	is, err := ValidateSVG(cmd.String("svg"), defaultStrokeTol, defaultSizeTolPx)
+	profile, err := svgcheck.ProfileForSVG(cmd.String("config"), cmd.String("profile"), cmd.String("svg"))
+	if err != nil {
+		return err
+	}
+
+	if cmd.Bool("fix") {
+		fixes, err := FixSVG(cmd.String("svg"), profile)
+		if err != nil {
+			return fmt.Errorf("failed to fix svg file: %w", err)
+		}
+		for _, f := range fixes {
+			log.Info().
+				Str("file", cmd.String("svg")).
+				Int("line", f.Line).
+				Str("element", f.Element).
+				Str("property", f.Property).
+				Str("old", f.Old).
+				Str("new", f.New).
+				Msg("fixed style")
+		}
+		log.Info().Str("file", cmd.String("svg")).Int("fixes", len(fixes)).Msg("fixed svg styles")
+	}
+
+	is, err := ValidateSVG(cmd.String("svg"), profile)
 	if err != nil {
 		return fmt.Errorf("invalid svg file: %w", err)
 	}
 	issues = append(issues, is...)
 
-	if len(issues) > 0 {
-		for _, it := range issues {
-			line := it.Line
-			if line <= 0 {
-				line = 1
-			}
-			log.Error().Int("line", line).Str("file", it.File).Msg(it.Msg)
+	failed := 0
+	for _, it := range issues {
+		line := it.Line
+		if line <= 0 {
+			line = 1
+		}
+		if it.Severity == svgcheck.SeverityWarning && !cmd.Bool("strict") {
+			log.Warn().Int("line", line).Str("file", it.File).Str("rule", it.Rule).Msg(it.Msg)
+			continue
 		}
-		return fmt.Errorf("%d issues", len(issues))
+		log.Error().Int("line", line).Str("file", it.File).Str("rule", it.Rule).Msg(it.Msg)
+		failed++
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d issues", failed)
 	}
 
 	return nil
 }
 
-func ValidateSVG(path string, strokeWidthTol, sizeTol float64) ([]Issue, error) {
-	f, err := os.Open(path)
+// drawable is a visible drawing element found while validating, with the byte range of
+// its start tag so that it can be rewritten by --fix.
+type drawable struct {
+	Name       string
+	Line       int
+	Start, End int64
+	Style      map[string]string
+}
+
+// scanResult is everything learnt about a document while validating it.
+type scanResult struct {
+	// Issues found, with severities set and anything turned off or suppressed removed
+	Issues []Issue
+
+	Drawables []drawable
+
+	// Suppressed rule IDs, from svg_check:disable comments
+	Suppressed map[string]bool
+}
+
+func ValidateSVG(path string, profile *svgcheck.Profile) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := validateSVG(path, data, profile)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	return res.Issues, nil
+}
 
-	dec := xml.NewDecoder(f)
+func validateSVG(path string, data []byte, profile *svgcheck.Profile) (*scanResult, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
 
 	var issues []Issue
+	suppressed := map[string]bool{}
 
 	// Visibility stack (effective hidden state), starting at "not hidden"
 	hiddenStack := []bool{false}
@@ -78,9 +136,31 @@ func ValidateSVG(path string, strokeWidthTol, sizeTol float64) ([]Issue, error)
 	// If we enter a hidden subtree, we skip *all* checks and style processing until we exit it.
 	skipDepth := 0
 
+	// Layer stack: the enclosing Inkscape layers (nil for elements that aren't layers).
+	// Layers are tracked even inside hidden subtrees, as that's where reference artwork lives.
+	layerStack := []*layer{nil}
+	var layers []*layer
+
+	// Depth within <defs>, where images (eg: in masks) are not reference artwork
+	defsDepth := 0
+
+	// Depth within <style>, whose stylesheet text is checked for external references
+	styleDepth := 0
+
 	seenRootSVG := false
 
+	// Visible drawables (including those rendered by <use>) and their total node count,
+	// for the single outline and node budget checks
+	var drawables []drawable
+	visibleDrawables := 0
+	nodes := 0
+
+	// Elements by id, built on demand to resolve <use>
+	var ids map[string]*node
+	useBudget := maxUseElements
+
 	for {
+		tokStart := dec.InputOffset()
 		tok, err := dec.Token()
 		if err == io.EOF {
 			break
@@ -90,6 +170,33 @@ func ValidateSVG(path string, strokeWidthTol, sizeTol float64) ([]Issue, error)
 		}
 
 		switch t := tok.(type) {
+		case xml.ProcInst:
+			if t.Target == "xml-stylesheet" {
+				issues = append(issues, Issue{File: path, Line: decoderLine(dec), Rule: svgcheck.RuleExternalReference, Msg: "<?xml-stylesheet?> is not allowed"})
+			}
+
+		case xml.CharData:
+			if styleDepth > 0 {
+				issues = append(issues, validateStylesheet(path, decoderLine(dec), string(t))...)
+			}
+
+		case xml.Comment:
+			ids, ok := parseSuppression(string(t))
+			if !ok {
+				continue
+			}
+			for _, id := range ids {
+				if _, known := svgcheck.DefaultSeverities[id]; !known {
+					issues = append(issues, Issue{File: path, Line: decoderLine(dec), Rule: svgcheck.RuleSuppression, Msg: fmt.Sprintf("svg_check:disable names unknown rule %q", id)})
+					continue
+				}
+				if svgcheck.Unsuppressible[id] {
+					issues = append(issues, Issue{File: path, Line: decoderLine(dec), Rule: svgcheck.RuleSuppression, Msg: fmt.Sprintf("svg_check:disable can't disable rule %q, it can only be changed in svg_check.json", id)})
+					continue
+				}
+				suppressed[id] = true
+			}
+
 		case xml.StartElement:
 			line := decoderLine(dec)
 
@@ -97,12 +204,36 @@ func ValidateSVG(path string, strokeWidthTol, sizeTol float64) ([]Issue, error)
 			parentHidden := hiddenStack[len(hiddenStack)-1]
 
 			attrs := t.Attr
-			thisHidden := elementHidden(attrs)
+			thisHidden := svgdoc.Hidden(attrs)
 			effectiveHidden := parentHidden || thisHidden
 
 			// Always push hidden state so EndElement pops stay aligned.
 			hiddenStack = append(hiddenStack, effectiveHidden)
 
+			// Scripts and external references are unsafe even when hidden
+			issues = append(issues, validateUntrusted(path, line, t, effectiveHidden)...)
+			if styleDepth > 0 || t.Name.Local == "style" {
+				styleDepth++
+			}
+
+			// Track layers, and which layer any <image> belongs to
+			enclosing := nearestLayer(layerStack)
+			thisLayer := layerFrom(line, t, effectiveHidden, enclosing == nil)
+			layerStack = append(layerStack, thisLayer)
+			if thisLayer != nil {
+				layers = append(layers, thisLayer)
+			}
+			if defsDepth > 0 || t.Name.Local == "defs" {
+				defsDepth++
+			}
+			if t.Name.Local == "image" && defsDepth == 0 {
+				if enclosing == nil {
+					issues = append(issues, Issue{File: path, Line: line, Rule: svgcheck.RuleReferenceLayer, Msg: fmt.Sprintf("<image> must be inside the %q layer", referenceLayerLabel)})
+				} else {
+					enclosing.Images++
+				}
+			}
+
 			// If we're already skipping, we do *nothing* (including style processing).
 			// Just track depth so we know when we leave the skipped subtree.
 			if skipDepth > 0 {
@@ -112,8 +243,8 @@ func ValidateSVG(path string, strokeWidthTol, sizeTol float64) ([]Issue, error)
 				continue
 			}
 
-			// NEW: ignore everything under <defs>
-			ignoreSubtree := t.Name.Local == "defs"
+			// NEW: ignore everything under <defs>, and symbols which are only rendered by <use>
+			ignoreSubtree := t.Name.Local == "defs" || t.Name.Local == "symbol"
 
 			// If this element is hidden, begin skipping its subtree entirely.
 			// We also keep styleStack aligned with a dummy push.
@@ -136,29 +267,67 @@ func ValidateSVG(path string, strokeWidthTol, sizeTol float64) ([]Issue, error)
 				w, okW := getAttr(attrs, "", "width")
 				h, okH := getAttr(attrs, "", "height")
 				if !okW || !okH {
-					issues = append(issues, Issue{File: path, Line: line, Msg: "root <svg> missing width/height attributes"})
+					issues = append(issues, Issue{File: path, Line: line, Rule: svgcheck.RuleCanvasSize, Msg: "root <svg> missing width/height attributes"})
 				} else {
+					size := profile.CanvasSize
 					wpx, errW := parsePxLength(w)
 					hpx, errH := parsePxLength(h)
 					if errW != nil || errH != nil {
-						msg := fmt.Sprintf("root <svg> width/height must be 70px/70px (got width=%q height=%q)", w, h)
-						issues = append(issues, Issue{File: path, Line: line, Msg: msg})
+						msg := fmt.Sprintf("root <svg> width/height must be %gpx/%gpx (got width=%q height=%q)", size, size, w, h)
+						issues = append(issues, Issue{File: path, Line: line, Rule: svgcheck.RuleCanvasSize, Msg: msg})
 					} else {
-						if !closeEnough(wpx, wantSizePx, sizeTol) || !closeEnough(hpx, wantSizePx, sizeTol) {
-							msg := fmt.Sprintf("root <svg> width/height must be 70px/70px (got width=%.6gpx height=%.6gpx)", wpx, hpx)
-							issues = append(issues, Issue{File: path, Line: line, Msg: msg})
+						if !closeEnough(wpx, size, profile.CanvasSizeTolerance) || !closeEnough(hpx, size, profile.CanvasSizeTolerance) {
+							msg := fmt.Sprintf("root <svg> width/height must be %gpx/%gpx (got width=%.6gpx height=%.6gpx)", size, size, wpx, hpx)
+							issues = append(issues, Issue{File: path, Line: line, Rule: svgcheck.RuleCanvasSize, Msg: msg})
 						}
 					}
 				}
 			}
 
 			// Element checks (only for visible elements)
+			issues = append(issues, validatePortable(path, line, t)...)
+
 			switch t.Name.Local {
 			case "image":
-				issues = append(issues, Issue{File: path, Line: line, Msg: "visible <image> found (reference artwork must be hidden)"})
+				issues = append(issues, Issue{File: path, Line: line, Rule: svgcheck.RuleVisibleImage, Msg: "visible <image> found (reference artwork must be hidden)"})
 
 			case "path", "rect", "circle", "ellipse", "polygon", "polyline", "line":
-				issues = append(issues, validateDrawable(path, line, t.Name.Local, thisStyle, strokeWidthTol)...)
+				drawStyle := resolveCurrentColor(thisStyle)
+				issues = append(issues, validateDrawable(path, line, t.Name.Local, drawStyle, profile)...)
+
+				drawables = append(drawables, drawable{
+					Name:  t.Name.Local,
+					Line:  line,
+					Start: tokStart,
+					End:   dec.InputOffset(),
+					Style: drawStyle,
+				})
+				visibleDrawables++
+				if visibleDrawables == 2 {
+					issues = append(issues, Issue{File: path, Line: line, Rule: svgcheck.RuleSingleOutline, Msg: "more than one visible drawable found (the outline should be a single path, see Path > Union)"})
+				}
+
+				if t.Name.Local == "path" {
+					d, _ := getAttr(attrs, "", "d")
+					is, n := validatePathData(path, line, d)
+					issues = append(issues, is...)
+					nodes += n
+				}
+
+			case "use":
+				if ids == nil {
+					if ids, err = indexIDs(data); err != nil {
+						return nil, err
+					}
+				}
+				res := expandUse(path, line, t, thisStyle, ids, profile, &useBudget)
+				issues = append(issues, res.Issues...)
+				nodes += res.Nodes
+				before := visibleDrawables
+				visibleDrawables += res.Drawables
+				if before < 2 && visibleDrawables >= 2 {
+					issues = append(issues, Issue{File: path, Line: line, Rule: svgcheck.RuleSingleOutline, Msg: "more than one visible drawable found (the outline should be a single path, see Path > Union)"})
+				}
 			}
 
 		case xml.EndElement:
@@ -167,6 +336,20 @@ func ValidateSVG(path string, strokeWidthTol, sizeTol float64) ([]Issue, error)
 				hiddenStack = hiddenStack[:len(hiddenStack)-1]
 			}
 
+			// Pop layer stack, checking the layer now that we know what it contains
+			if len(layerStack) > 1 {
+				if l := layerStack[len(layerStack)-1]; l != nil {
+					issues = append(issues, validateLayer(path, l)...)
+				}
+				layerStack = layerStack[:len(layerStack)-1]
+			}
+			if defsDepth > 0 {
+				defsDepth--
+			}
+			if styleDepth > 0 {
+				styleDepth--
+			}
+
 			// Pop style stack (kept aligned even when skipping)
 			if len(styleStack) > 1 {
 				styleStack = styleStack[:len(styleStack)-1]
@@ -181,73 +364,109 @@ func ValidateSVG(path string, strokeWidthTol, sizeTol float64) ([]Issue, error)
 
 	// If the SVG never had a root <svg>, it’s malformed (but the parser would likely have errored)
 	if !seenRootSVG {
-		issues = append(issues, Issue{File: path, Line: 1, Msg: "no <svg> root element found"})
+		issues = append(issues, Issue{File: path, Line: 1, Rule: svgcheck.RuleSVGRoot, Msg: "no <svg> root element found"})
 	}
 
-	return issues, nil
-}
+	if seenRootSVG {
+		issues = append(issues, validateOutlineLayers(path, layers)...)
+	}
 
-func validateDrawable(file string, line int, name string, style map[string]string, strokeWidthTol float64) []Issue {
-	var issues []Issue
+	if profile.MaxNodes > 0 && nodes > profile.MaxNodes {
+		issues = append(issues, Issue{File: path, Line: 1, Rule: svgcheck.RuleNodeBudget, Msg: fmt.Sprintf("visible paths have %d nodes, which exceeds the budget of %d (remove unnecessary nodes)", nodes, profile.MaxNodes)})
+	}
 
-	// Normalise colours to lowercase
-	fill := strings.ToLower(strings.TrimSpace(style["fill"]))
-	stroke := strings.ToLower(strings.TrimSpace(style["stroke"]))
+	return &scanResult{
+		Issues:     applyProfile(issues, profile, suppressed),
+		Drawables:  drawables,
+		Suppressed: suppressed,
+	}, nil
+}
 
-	if fill != wantFill {
-		issues = append(issues, Issue{File: file, Line: line, Msg: fmt.Sprintf("<%s> fill must be %s (got %q)", name, wantFill, fill)})
-	}
-	if stroke != wantStroke {
-		issues = append(issues, Issue{File: file, Line: line, Msg: fmt.Sprintf("<%s> stroke must be %s (got %q)", name, wantStroke, stroke)})
-	}
+// styleRule is a required style property for visible drawables. The property name doubles as the rule ID.
+// Check returns a description of the problem, or "" if the value is acceptable. --fix writes the
+// value from the profile's OutlineStyle.
+type styleRule struct {
+	Key   string
+	Check func(name, value string, p *svgcheck.Profile) string
+}
 
-	// stroke-width: numeric, allow close enough
-	swStr := strings.TrimSpace(style["stroke-width"])
-	if swStr == "" {
-		issues = append(issues, Issue{File: file, Line: line, Msg: fmt.Sprintf("<%s> missing stroke-width", name)})
-	} else {
-		sw, err := parseNumber(swStr)
+var styleRules = []styleRule{
+	{
+		Key:   svgcheck.RuleFill,
+		Check: paintCheck(svgcheck.RuleFill, func(p *svgcheck.Profile) string { return p.Fill }),
+	},
+	{
+		Key:   svgcheck.RuleStroke,
+		Check: paintCheck(svgcheck.RuleStroke, func(p *svgcheck.Profile) string { return p.Stroke }),
+	},
+	{
+		Key: svgcheck.RuleStrokeWidth,
+		Check: func(name, v string, p *svgcheck.Profile) string {
+			// stroke-width: numeric, allow close enough
+			v = strings.TrimSpace(v)
+			if v == "" {
+				return fmt.Sprintf("<%s> missing stroke-width", name)
+			}
+			sw, err := parseNumber(v)
+			if err != nil {
+				return fmt.Sprintf("<%s> invalid stroke-width %q", name, v)
+			}
+			if !closeEnough(sw, p.StrokeWidth, p.StrokeWidthTolerance) {
+				return fmt.Sprintf("<%s> stroke-width must be %.8f (got %.8f)", name, p.StrokeWidth, sw)
+			}
+			return ""
+		},
+	},
+	{
+		Key:   svgcheck.RuleStrokeOpacity,
+		Check: opacityCheck(svgcheck.RuleStrokeOpacity),
+	},
+	{
+		Key:   svgcheck.RuleFillOpacity,
+		Check: opacityCheck(svgcheck.RuleFillOpacity),
+	},
+}
+
+// paintCheck compares a fill or stroke by colour value, so that eg: white, #fff and rgb(255,255,255) are all accepted for #ffffff.
+func paintCheck(key string, want func(p *svgcheck.Profile) string) func(name, v string, p *svgcheck.Profile) string {
+	return func(name, v string, p *svgcheck.Profile) string {
+		v = strings.TrimSpace(v)
+		if svgcheck.IsPaintServer(v) {
+			return fmt.Sprintf("<%s> %s %s is a paint server, paint servers (gradients and patterns) are not allowed, use a flat colour", name, key, v)
+		}
+		got, err := svgcheck.ParseColour(v)
 		if err != nil {
-			issues = append(issues, Issue{File: file, Line: line, Msg: fmt.Sprintf("<%s> invalid stroke-width %q", name, swStr)})
-		} else if !closeEnough(sw, wantStrokeWidth, strokeWidthTol) {
-			issues = append(issues, Issue{File: file, Line: line, Msg: fmt.Sprintf("<%s> stroke-width must be %.8f (got %.8f)", name, wantStrokeWidth, sw)})
+			return fmt.Sprintf("<%s> %s must be %s (got %q)", name, key, want(p), v)
 		}
+		// the profile's colour is checked when it is loaded
+		if w, _ := svgcheck.ParseColour(want(p)); got != w {
+			return fmt.Sprintf("<%s> %s must be %s (got %q)", name, key, want(p), v)
+		}
+		return ""
 	}
+}
 
-	// Opacities
-	if v := strings.TrimSpace(style["stroke-opacity"]); v == "" {
-		issues = append(issues, Issue{File: file, Line: line, Msg: fmt.Sprintf("<%s> missing stroke-opacity", name)})
-	} else if op, err := parseNumber(v); err != nil || !closeEnough(op, wantOpacity, 0.0001) {
-		issues = append(issues, Issue{File: file, Line: line, Msg: fmt.Sprintf("<%s> stroke-opacity must be 1 (got %q)", name, v)})
-	}
-
-	if v := strings.TrimSpace(style["fill-opacity"]); v == "" {
-		issues = append(issues, Issue{File: file, Line: line, Msg: fmt.Sprintf("<%s> missing fill-opacity", name)})
-	} else if op, err := parseNumber(v); err != nil || !closeEnough(op, wantOpacity, 0.0001) {
-		issues = append(issues, Issue{File: file, Line: line, Msg: fmt.Sprintf("<%s> fill-opacity must be 1 (got %q)", name, v)})
+func opacityCheck(key string) func(name, v string, _ *svgcheck.Profile) string {
+	return func(name, v string, _ *svgcheck.Profile) string {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return fmt.Sprintf("<%s> missing %s", name, key)
+		}
+		if op, err := parseNumber(v); err != nil || !closeEnough(op, wantOpacity, 0.0001) {
+			return fmt.Sprintf("<%s> %s must be 1 (got %q)", name, key, v)
+		}
+		return ""
 	}
-
-	return issues
 }
 
-// elementHidden returns true if this element is hidden by its own attributes/style.
-// Note: ancestral hidden state is handled by the stack in ValidateSVG.
-func elementHidden(attrs []xml.Attr) bool {
-	// direct attrs
-	if v, ok := getAttr(attrs, "", "display"); ok && strings.TrimSpace(v) == "none" {
-		return true
-	}
-	if v, ok := getAttr(attrs, "", "visibility"); ok && strings.TrimSpace(v) == "hidden" {
-		return true
-	}
-	// style attr
-	if style, ok := getAttr(attrs, "", "style"); ok {
-		s := parseStyle(style)
-		if strings.TrimSpace(s["display"]) == "none" || strings.TrimSpace(s["visibility"]) == "hidden" {
-			return true
+func validateDrawable(file string, line int, name string, style map[string]string, profile *svgcheck.Profile) []Issue {
+	var issues []Issue
+	for _, r := range styleRules {
+		if msg := r.Check(name, style[r.Key], profile); msg != "" {
+			issues = append(issues, Issue{File: file, Line: line, Rule: r.Key, Msg: msg})
 		}
 	}
-	return false
+	return issues
 }
 
 // styleFrom builds a property map from this element's attributes + style="...".
@@ -263,7 +482,7 @@ func styleFrom(attrs []xml.Attr) map[string]string {
 
 	// presentation attributes override style
 	for _, key := range []string{
-		"fill", "stroke", "stroke-width", "stroke-opacity", "fill-opacity",
+		"fill", "stroke", "stroke-width", "stroke-opacity", "fill-opacity", "color",
 	} {
 		if v, ok := getAttr(attrs, "", key); ok {
 			out[key] = v
@@ -279,13 +498,33 @@ func mergeStyles(parent, child map[string]string) map[string]string {
 	for k, v := range parent {
 		out[k] = v
 	}
-	// apply child overrides
+	// apply child overrides, resolving "inherit" to the parent's value
 	for k, v := range child {
+		if strings.EqualFold(strings.TrimSpace(v), "inherit") {
+			if pv, ok := parent[k]; ok {
+				out[k] = pv
+			} else {
+				delete(out, k)
+			}
+			continue
+		}
 		out[k] = v
 	}
 	return out
 }
 
+// resolveCurrentColor returns a copy of the style with any currentColor paint replaced by the color property.
+// This is done per element rather than in mergeStyles, as currentColor is inherited as the keyword.
+func resolveCurrentColor(style map[string]string) map[string]string {
+	out := mergeStyles(style, nil)
+	for _, k := range []string{"fill", "stroke"} {
+		if strings.EqualFold(strings.TrimSpace(style[k]), "currentcolor") {
+			out[k] = style["color"]
+		}
+	}
+	return out
+}
+
 func parseStyle(s string) map[string]string {
 	out := map[string]string{}
 	parts := strings.Split(s, ";")
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+)
+
+// Fix is a single property rewritten by FixSVG.
+type Fix struct {
+	Line     int
+	Element  string
+	Property string
+	Old, New string
+}
+
+// FixSVG rewrites the style of visible drawables that don't meet the styling rules.
+// Only the offending start tags are touched; every other byte of the file is preserved.
+// The file is replaced atomically, and only if something changed.
+// Rules that are turned off or suppressed are left alone.
+func FixSVG(path string, profile *svgcheck.Profile) ([]Fix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := validateSVG(path, data, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		fixes []Fix
+		out   bytes.Buffer
+		last  int64
+	)
+	for _, d := range res.Drawables {
+		tag, fs := fixStartTag(data[d.Start:d.End], d, profile, res.Suppressed)
+		if len(fs) == 0 {
+			continue
+		}
+		out.Write(data[last:d.Start])
+		out.Write(tag)
+		last = d.End
+		fixes = append(fixes, fs...)
+	}
+	if len(fixes) == 0 {
+		return nil, nil
+	}
+	out.Write(data[last:])
+
+	if err := svgdoc.WriteFileAtomic(path, out.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to write fixed svg: %w", err)
+	}
+	return fixes, nil
+}
+
+// fixStartTag returns a copy of the raw start tag with each failing style rule set to its required value.
+// Presentation attributes are rewritten in place (as they take precedence), otherwise the property
+// is set in the element's own style attribute, which is created if needed.
+func fixStartTag(raw []byte, d drawable, profile *svgcheck.Profile, suppressed map[string]bool) ([]byte, []Fix) {
+	attrs := svgdoc.ScanRawAttrs(raw)
+
+	var (
+		fixes      []Fix
+		edits      = map[string]svgdoc.RawAttr{} // presentation attributes to rewrite, keyed by name
+		styleEdits = map[string]string{}         // style properties to set
+		styleKeys  []string                      // ... in rule order, for stable output
+		style      = profile.OutlineStyle()
+	)
+	for _, r := range styleRules {
+		old := d.Style[r.Key]
+		if !enabled(profile, r.Key, suppressed) || r.Check(d.Name, old, profile) == "" {
+			continue
+		}
+		want := style[r.Key]
+		fixes = append(fixes, Fix{Line: d.Line, Element: d.Name, Property: r.Key, Old: old, New: want})
+		if a, ok := attrs[r.Key]; ok {
+			a.Value = want
+			edits[r.Key] = a
+			continue
+		}
+		styleEdits[r.Key] = want
+		styleKeys = append(styleKeys, r.Key)
+	}
+	if len(fixes) == 0 {
+		return raw, nil
+	}
+
+	if len(styleKeys) > 0 {
+		if a, ok := attrs["style"]; ok {
+			a.Value = svgdoc.SetStyleProperties(a.Value, styleKeys, styleEdits)
+			edits["style"] = a
+		} else {
+			// no style attribute: add one just before the end of the tag
+			end := len(raw) - 1
+			if end > 0 && raw[end-1] == '/' {
+				end--
+			}
+			decls := make([]string, 0, len(styleKeys))
+			for _, k := range styleKeys {
+				decls = append(decls, k+":"+styleEdits[k])
+			}
+			edits["style"] = svgdoc.RawAttr{ValueStart: end, ValueEnd: end, Value: ` style="` + strings.Join(decls, ";") + `"`}
+		}
+	}
+
+	// apply edits back to front so earlier offsets stay valid
+	out := append([]byte(nil), raw...)
+	for len(edits) > 0 {
+		var key string
+		for k, a := range edits {
+			if key == "" || a.ValueStart > edits[key].ValueStart {
+				key = k
+			}
+		}
+		a := edits[key]
+		delete(edits, key)
+		out = append(out[:a.ValueStart], append([]byte(a.Value), out[a.ValueEnd:]...)...)
+	}
+	return out, fixes
+}
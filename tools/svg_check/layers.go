@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+)
+
+// referenceLayerLabel is the layer name that scripts use to find the reference artwork.
+const referenceLayerLabel = "Reference Artwork"
+
+// layer is an Inkscape layer (a <g inkscape:groupmode="layer">) seen while walking the document.
+type layer struct {
+	Line     int
+	Label    string
+	Hidden   bool // hidden by its own display:none
+	Visible  bool // effectively visible, taking ancestors into account
+	Locked   bool
+	TopLevel bool // not nested inside another layer
+	Images   int  // number of <image> elements inside this layer (excluding sublayers)
+}
+
+// layerFrom returns a layer if the element is an Inkscape layer, otherwise nil.
+func layerFrom(line int, t xml.StartElement, effectiveHidden, topLevel bool) *layer {
+	if t.Name.Local != "g" {
+		return nil
+	}
+	if mode, ok := getAttr(t.Attr, inkscapeNS, "groupmode"); !ok || mode != "layer" {
+		return nil
+	}
+
+	l := &layer{
+		Line:     line,
+		Visible:  !effectiveHidden,
+		TopLevel: topLevel,
+	}
+	l.Label, _ = getAttr(t.Attr, inkscapeNS, "label")
+	if v, ok := getAttr(t.Attr, sodipodiNS, "insensitive"); ok && strings.TrimSpace(v) == "true" {
+		l.Locked = true
+	}
+	if v, ok := getAttr(t.Attr, "", "display"); ok && strings.TrimSpace(v) == "none" {
+		l.Hidden = true
+	}
+	if style, ok := getAttr(t.Attr, "", "style"); ok && strings.TrimSpace(parseStyle(style)["display"]) == "none" {
+		l.Hidden = true
+	}
+	return l
+}
+
+// nearestLayer returns the innermost layer on the stack, or nil if not inside a layer.
+func nearestLayer(stack []*layer) *layer {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] != nil {
+			return stack[i]
+		}
+	}
+	return nil
+}
+
+// validateLayer checks the reference artwork conventions for a layer once its contents are known.
+func validateLayer(file string, l *layer) []Issue {
+	if l.Images == 0 {
+		return nil
+	}
+
+	var issues []Issue
+	if l.Label != referenceLayerLabel {
+		issues = append(issues, Issue{File: file, Line: l.Line, Rule: svgcheck.RuleReferenceLayer, Msg: fmt.Sprintf("layer containing <image> must be named %q (got %q)", referenceLayerLabel, l.Label)})
+	}
+	if !l.Hidden {
+		issues = append(issues, Issue{File: file, Line: l.Line, Rule: svgcheck.RuleReferenceLayer, Msg: fmt.Sprintf("layer %q containing <image> must be hidden (display:none)", l.Label)})
+	}
+	if !l.Locked {
+		issues = append(issues, Issue{File: file, Line: l.Line, Rule: svgcheck.RuleReferenceLayer, Msg: fmt.Sprintf("layer %q containing <image> must be locked (sodipodi:insensitive)", l.Label)})
+	}
+	return issues
+}
+
+// validateOutlineLayers checks there is exactly one visible top-level layer holding the outline.
+func validateOutlineLayers(file string, layers []*layer) []Issue {
+	var visible []*layer
+	for _, l := range layers {
+		if l.TopLevel && l.Visible {
+			visible = append(visible, l)
+		}
+	}
+
+	switch len(visible) {
+	case 0:
+		return []Issue{{File: file, Line: 1, Rule: svgcheck.RuleOutlineLayer, Msg: "no visible outline layer found"}}
+	case 1:
+		return nil
+	}
+
+	labels := make([]string, 0, len(visible))
+	for _, l := range visible {
+		labels = append(labels, fmt.Sprintf("%q", l.Label))
+	}
+	return []Issue{{File: file, Line: visible[1].Line, Rule: svgcheck.RuleOutlineLayer, Msg: fmt.Sprintf("exactly one visible outline layer is allowed (got %d: %s)", len(visible), strings.Join(labels, ", "))}}
+}
@@ -18,6 +18,22 @@ var cmd = &cli.Command{
 			Usage:    "Path to the input svg file",
 			Required: true,
 		},
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "Path to the svg_check config file defining rule profiles (default: the first svg_check.json in the svg's directory or above it)",
+		},
+		&cli.StringFlag{
+			Name:  "profile",
+			Usage: "Name of the profile to validate against (default: chosen by the config file)",
+		},
+		&cli.BoolFlag{
+			Name:  "fix",
+			Usage: "Rewrite non-conforming styles of visible drawables in place before validating",
+		},
+		&cli.BoolFlag{
+			Name:  "strict",
+			Usage: "Treat warnings (eg: path hygiene) as errors",
+		},
 	},
 }
 
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+	"github.com/plane-watch/pw-silhouettes/internal/svgpath"
+)
+
+// validatePathData checks an outline path for hygiene problems: unclosed subpaths,
+// and duplicate or zero-length nodes.
+// It returns the issues found and the number of nodes in the path, for the node budget.
+func validatePathData(file string, line int, d string) ([]Issue, int) {
+	subpaths, err := svgpath.Parse(d)
+	if err != nil {
+		return []Issue{{File: file, Line: line, Rule: svgcheck.RulePathData, Msg: fmt.Sprintf("<path> invalid path data: %v", err)}}, 0
+	}
+	if len(subpaths) == 0 {
+		return []Issue{{File: file, Line: line, Rule: svgcheck.RulePathData, Msg: "<path> has no path data"}}, 0
+	}
+
+	var issues []Issue
+	nodes := 0
+
+	for i, sp := range subpaths {
+		nodes += sp.Nodes()
+
+		if !sp.Closed {
+			issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleUnclosedPath, Msg: fmt.Sprintf("<path> subpath %d starting at (%.6g,%.6g) is not closed", i+1, sp.Start.X, sp.Start.Y)})
+		}
+
+		for j, seg := range sp.Segments {
+			switch {
+			case seg.Cmd == 'A' && seg.Start.Near(seg.End):
+				// SVG draws nothing for an arc that ends where it starts
+				issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleZeroLengthSegment, Msg: fmt.Sprintf("<path> subpath %d segment %d at (%.6g,%.6g) has zero length", i+1, j+1, seg.End.X, seg.End.Y)})
+			case seg.ZeroLength():
+				// a curve that ends where it starts but has control points elsewhere is a loop, not a duplicate
+				issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleDuplicateNode, Msg: fmt.Sprintf("<path> subpath %d segment %d has duplicate consecutive nodes at (%.6g,%.6g)", i+1, j+1, seg.End.X, seg.End.Y)})
+			}
+		}
+	}
+
+	return issues, nodes
+}
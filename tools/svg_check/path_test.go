@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+)
+
+func TestValidatePathData(t *testing.T) {
+	tests := []struct {
+		name  string
+		d     string
+		rules []string
+		nodes int
+	}{
+		{"closed", "M10 10 h10 v10 h-10 z", nil, 4},
+		{"closed with the final node on the first", "M10 10 h10 v10 h-10 v-10 z", nil, 4},
+		{"unclosed", "M10 10 h10 v10 h-10", []string{svgcheck.RuleUnclosedPath}, 4},
+		{"each unclosed subpath", "M10 10 h10 v10 z M30 30 h10 M50 50 h10", []string{svgcheck.RuleUnclosedPath, svgcheck.RuleUnclosedPath}, 7},
+		{"line to the same point", "M10 10 h10 l0 0 v10 z", []string{svgcheck.RuleDuplicateNode}, 4},
+		{"curve with no extent", "M10 10 h10 c0 0 0 0 0 0 v10 z", []string{svgcheck.RuleDuplicateNode}, 4},
+		{"curve that loops back to its start", "M10 10 h10 c10 -5 10 5 0 0 v10 z", nil, 4},
+		{"quadratic that goes out and back", "M10 10 h10 q5 5 0 0 v10 z", nil, 4},
+		{"arc that ends where it starts", "M10 10 h10 a5 5 0 0 1 0 0 v10 z", []string{svgcheck.RuleZeroLengthSegment}, 4},
+		{"arcs", "M10 10 a5 5 0 0 1 10 0 a5 5 0 0 1 -10 0 z", nil, 2},
+		{"invalid", "M10 10 h", []string{svgcheck.RulePathData}, 0},
+		{"empty", "", []string{svgcheck.RulePathData}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, nodes := validatePathData("test.svg", 1, tt.d)
+			var rules []string
+			for _, is := range issues {
+				rules = append(rules, is.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("validatePathData(%q) reported %v, want %v", tt.d, rules, tt.rules)
+			}
+			if nodes != tt.nodes {
+				t.Errorf("validatePathData(%q) counted %d nodes, want %d", tt.d, nodes, tt.nodes)
+			}
+		})
+	}
+}
+
+func TestNodeBudget(t *testing.T) {
+	// a closed zigzag of n nodes, as a silhouette in the default profile's style
+	svg := func(n int) []byte {
+		var d strings.Builder
+		d.WriteString("M5 5")
+		for i := 1; i < n-1; i++ {
+			fmt.Fprintf(&d, " L%g %d", 5+60*float64(i)/float64(n), 5+10*(i%2))
+		}
+		d.WriteString(" L35 65 Z")
+		return fmt.Appendf(nil, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" width="70px" height="70px" viewBox="0 0 70 70">
+  <g inkscape:groupmode="layer" inkscape:label="Outline">
+    <path style="fill:#ffffff;fill-opacity:1;stroke:#000000;stroke-width:0.26458333;stroke-opacity:1" d="%s"/>
+  </g>
+</svg>`, d.String())
+	}
+
+	p := svgcheck.DefaultProfile()
+	tests := []struct {
+		nodes int
+		want  bool
+	}{
+		{p.MaxNodes, false},
+		{p.MaxNodes + 1, true},
+		{p.MaxNodes * 2, true},
+		{10, false},
+	}
+
+	for _, tt := range tests {
+		res, err := validateSVG("test.svg", svg(tt.nodes), p)
+		if err != nil {
+			t.Fatalf("validateSVG failed: %v", err)
+		}
+		got := false
+		for _, is := range res.Issues {
+			if is.Rule == svgcheck.RuleNodeBudget {
+				got = true
+			}
+		}
+		if got != tt.want {
+			t.Errorf("with %d nodes, node-budget reported = %v, want %v (issues: %+v)", tt.nodes, got, tt.want, res.Issues)
+		}
+	}
+}
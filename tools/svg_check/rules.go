@@ -0,0 +1,41 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+)
+
+// suppressionRe matches <!-- svg_check:disable rule-a rule-b --> comments
+var suppressionRe = regexp.MustCompile(`^\s*svg_check:disable\s+(.*?)\s*$`)
+
+// parseSuppression returns the rule IDs disabled by an XML comment, if it is a suppression comment.
+func parseSuppression(comment string) ([]string, bool) {
+	m := suppressionRe.FindStringSubmatch(comment)
+	if m == nil {
+		return nil, false
+	}
+	return strings.FieldsFunc(m[1], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}), true
+}
+
+// enabled returns true if a rule is neither turned off by the profile nor suppressed. Unsuppressible
+// rules are never treated as suppressed.
+func enabled(p *svgcheck.Profile, rule string, suppressed map[string]bool) bool {
+	return p.Severity(rule) != svgcheck.SeverityOff && (!suppressed[rule] || svgcheck.Unsuppressible[rule])
+}
+
+// applyProfile sets the severity of each issue from the profile and drops those that are off or suppressed.
+func applyProfile(issues []Issue, p *svgcheck.Profile, suppressed map[string]bool) []Issue {
+	out := issues[:0]
+	for _, it := range issues {
+		if !enabled(p, it.Rule, suppressed) {
+			continue
+		}
+		it.Severity = p.Severity(it.Rule)
+		out = append(out, it)
+	}
+	return out
+}
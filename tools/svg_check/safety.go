@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+)
+
+// cssURLRe matches url(...) references in attribute values and stylesheets
+var cssURLRe = regexp.MustCompile(`url\(\s*['"]?([^'")]*?)['"]?\s*\)`)
+
+// isLocalRef returns true for references that stay within the document: fragment identifiers and data: URLs.
+func isLocalRef(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	return strings.HasPrefix(ref, "#") || strings.HasPrefix(strings.ToLower(ref), "data:")
+}
+
+// isReferenceLink returns true for links to a reference image in the reference directory, as
+// written by svg_reference.
+func isReferenceLink(ref string) bool {
+	_, ok := svgdoc.ReferenceLink(ref)
+	return ok
+}
+
+// externalRefs returns any references in an attribute or stylesheet value that point outside the document.
+func externalRefs(v string) []string {
+	var out []string
+	for _, m := range cssURLRe.FindAllStringSubmatch(v, -1) {
+		if !isLocalRef(m[1]) {
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
+
+// validateUntrusted checks an element for content that is unsafe wherever it appears,
+// including hidden layers and <defs>: scripts, event handlers and external references.
+// Hidden content is still parsed (and scripts still run) when the SVG is opened elsewhere.
+// The one exception is a hidden <image> linking to a file relative to the SVG, which is how
+// svg_reference leaves extracted reference artwork.
+func validateUntrusted(file string, line int, t xml.StartElement, hidden bool) []Issue {
+	var issues []Issue
+
+	if t.Name.Local == "script" {
+		issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleScript, Msg: "<script> is not allowed"})
+	}
+
+	for _, a := range t.Attr {
+		name := a.Name.Local
+		if a.Name.Space == "" && len(name) > 2 && strings.EqualFold(name[:2], "on") {
+			issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleScript, Msg: fmt.Sprintf("<%s> event handler attribute %q is not allowed", t.Name.Local, name)})
+			continue
+		}
+		if name == "href" {
+			if !isLocalRef(a.Value) && !(hidden && t.Name.Local == "image" && isReferenceLink(a.Value)) {
+				issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleExternalReference, Msg: fmt.Sprintf("<%s> refers to external resource %q", t.Name.Local, a.Value)})
+			}
+			continue
+		}
+		for _, ref := range externalRefs(a.Value) {
+			issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleExternalReference, Msg: fmt.Sprintf("<%s> %s refers to external resource %q", t.Name.Local, name, ref)})
+		}
+	}
+
+	return issues
+}
+
+// validateStylesheet checks the contents of a <style> element for external references.
+func validateStylesheet(file string, line int, css string) []Issue {
+	var issues []Issue
+	if strings.Contains(strings.ToLower(css), "@import") {
+		issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleExternalReference, Msg: "<style> @import is not allowed"})
+	}
+	for _, ref := range externalRefs(css) {
+		issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleExternalReference, Msg: fmt.Sprintf("<style> refers to external resource %q", ref)})
+	}
+	return issues
+}
+
+// validatePortable checks a visible element for content that doesn't render consistently
+// across Inkscape, browsers and the spritesheet: embedded HTML, text, filters, masks and clipping.
+func validatePortable(file string, line int, t xml.StartElement) []Issue {
+	var issues []Issue
+
+	switch t.Name.Local {
+	case "foreignObject":
+		issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleForeignObject, Msg: "visible <foreignObject> is not allowed"})
+	case "text":
+		issues = append(issues, Issue{File: file, Line: line, Rule: svgcheck.RuleText, Msg: "visible <text> is not allowed (rendering depends on installed fonts, convert it to a path)"})
+	}
+
+	for _, p := range []struct {
+		Key  string
+		Rule string
+	}{
+		{"filter", svgcheck.RuleFilter},
+		{"mask", svgcheck.RuleMask},
+		{"clip-path", svgcheck.RuleClipPath},
+	} {
+		if v, ok := ownProperty(t.Attr, p.Key); ok && v != "none" {
+			issues = append(issues, Issue{File: file, Line: line, Rule: p.Rule, Msg: fmt.Sprintf("<%s> uses %s %s, which is not allowed in visible artwork", t.Name.Local, p.Key, v)})
+		}
+	}
+
+	return issues
+}
+
+// ownProperty returns a property set directly on an element, by presentation attribute or style.
+func ownProperty(attrs []xml.Attr, key string) (string, bool) {
+	if v, ok := getAttr(attrs, "", key); ok {
+		return strings.TrimSpace(v), true
+	}
+	if style, ok := getAttr(attrs, "", "style"); ok {
+		if v, ok := parseStyle(style)[key]; ok {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
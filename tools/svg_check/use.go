@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+)
+
+// node is an element of the document tree, built only when a <use> needs resolving.
+type node struct {
+	Name     string
+	Attr     []xml.Attr
+	Children []*node
+}
+
+func (n *node) startElement() xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: n.Name}, Attr: n.Attr}
+}
+
+// indexIDs parses the document into a tree and returns its elements by id.
+func indexIDs(data []byte) (map[string]*node, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	ids := map[string]*node{}
+	stack := []*node{{}}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xml parse error: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{Name: t.Name.Local, Attr: t.Attr}
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, n)
+			stack = append(stack, n)
+			if id, ok := getAttr(t.Attr, "", "id"); ok {
+				ids[id] = n
+			}
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+const (
+	// maxUseElements is how many elements <use> may render in a document in all. Each <use> can render
+	// the same content more than once, so nested <use> can otherwise render exponentially many.
+	maxUseElements = 1000
+
+	// maxUseDepth is how deeply <use> may be nested.
+	maxUseDepth = 32
+)
+
+// useResult is what was found when expanding a <use>.
+type useResult struct {
+	Issues    []Issue
+	Drawables int // visible drawables rendered by the <use>
+	Nodes     int // path nodes rendered by the <use>
+}
+
+// expandUse validates the content rendered by a visible <use> as if it were inline, with the style
+// inherited from the <use> element. Issues are reported at the line of the <use>. budget is how many
+// more elements <use> may render in the document; once it runs out, an issue is reported and
+// nothing more is expanded.
+func expandUse(file string, line int, use xml.StartElement, useStyle map[string]string, ids map[string]*node, profile *svgcheck.Profile, budget *int) useResult {
+	var res useResult
+	if *budget > 0 {
+		visitUse(file, line, use.Attr, useStyle, ids, profile, map[*node]bool{}, budget, &res)
+	}
+	return res
+}
+
+func visitUse(file string, line int, attrs []xml.Attr, style map[string]string, ids map[string]*node, profile *svgcheck.Profile, seen map[*node]bool, budget *int, res *useResult) {
+	href, _ := getAttr(attrs, "", "href")
+	id, local := strings.CutPrefix(strings.TrimSpace(href), "#")
+	if !local {
+		// external references are reported by validateUntrusted
+		return
+	}
+
+	target, ok := ids[id]
+	if !ok {
+		res.Issues = append(res.Issues, Issue{File: file, Line: line, Rule: svgcheck.RuleUseReference, Msg: fmt.Sprintf("<use> refers to missing element #%s", id)})
+		return
+	}
+	if seen[target] {
+		res.Issues = append(res.Issues, Issue{File: file, Line: line, Rule: svgcheck.RuleUseReference, Msg: fmt.Sprintf("<use> of #%s refers back to itself", id)})
+		return
+	}
+	// seen is the chain of <use> being expanded, so its length is how deeply they're nested
+	if len(seen) >= maxUseDepth {
+		res.Issues = append(res.Issues, Issue{File: file, Line: line, Rule: svgcheck.RuleUseReference, Msg: fmt.Sprintf("<use> of #%s is nested more than %d deep", id, maxUseDepth)})
+		return
+	}
+	seen[target] = true
+	defer delete(seen, target)
+
+	visitUsed(file, line, id, target, style, ids, profile, seen, budget, res)
+}
+
+// visitUsed validates an element rendered through a <use> of #id, and its children.
+func visitUsed(file string, line int, id string, n *node, parentStyle map[string]string, ids map[string]*node, profile *svgcheck.Profile, seen map[*node]bool, budget *int, res *useResult) {
+	if svgdoc.Hidden(n.Attr) || *budget <= 0 {
+		return
+	}
+	if *budget--; *budget == 0 {
+		res.Issues = append(res.Issues, Issue{File: file, Line: line, Rule: svgcheck.RuleUseReference, Msg: fmt.Sprintf("<use> renders more than %d elements in all, so the rest weren't checked", maxUseElements)})
+		return
+	}
+
+	// prefix messages so it's clear the problem is in the referenced content
+	add := func(is ...Issue) {
+		for _, it := range is {
+			it.Line = line
+			it.Msg = fmt.Sprintf("via <use> of #%s: %s", id, it.Msg)
+			res.Issues = append(res.Issues, it)
+		}
+	}
+
+	style := mergeStyles(parentStyle, styleFrom(n.Attr))
+	add(validatePortable(file, line, n.startElement())...)
+
+	switch n.Name {
+	case "defs":
+		// not rendered, even when used
+		return
+
+	case "image":
+		add(Issue{File: file, Line: line, Rule: svgcheck.RuleVisibleImage, Msg: "visible <image> found (reference artwork must be hidden)"})
+
+	case "use":
+		visitUse(file, line, n.Attr, style, ids, profile, seen, budget, res)
+
+	case "path", "rect", "circle", "ellipse", "polygon", "polyline", "line":
+		add(validateDrawable(file, line, n.Name, resolveCurrentColor(style), profile)...)
+		res.Drawables++
+		if n.Name == "path" {
+			d, _ := getAttr(n.Attr, "", "d")
+			is, nodes := validatePathData(file, line, d)
+			add(is...)
+			res.Nodes += nodes
+		}
+	}
+
+	for _, c := range n.Children {
+		visitUsed(file, line, id, c, style, ids, profile, seen, budget, res)
+	}
+}
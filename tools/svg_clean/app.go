@@ -0,0 +1,87 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+func runApp(_ context.Context, cmd *cli.Command) error {
+
+	outDir := cmd.String("output_dir")
+	outZip := cmd.String("output_zip")
+	if outDir == "" && outZip == "" {
+		return fmt.Errorf("at least one of --output_dir or --output_zip is required")
+	}
+
+	files, err := svgdoc.Files(cmd.StringSlice("input"))
+	if err != nil {
+		return err
+	}
+
+	if outDir != "" {
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output dir: %w", err)
+		}
+	}
+
+	var zw *zip.Writer
+	if outZip != "" {
+		f, err := os.Create(outZip)
+		if err != nil {
+			return fmt.Errorf("failed to create zip: %w", err)
+		}
+		defer f.Close()
+		zw = zip.NewWriter(f)
+	}
+
+	var before, after int
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read svg: %w", err)
+		}
+
+		clean, err := Clean(data, int(cmd.Int("precision")))
+		if err != nil {
+			return fmt.Errorf("failed to clean %s: %w", file, err)
+		}
+
+		name := filepath.Base(file)
+		log.Info().Str("file", file).Int("before", len(data)).Int("after", len(clean)).Msg("cleaned svg")
+		before += len(data)
+		after += len(clean)
+
+		if outDir != "" {
+			if err := os.WriteFile(filepath.Join(outDir, name), clean, 0644); err != nil {
+				return fmt.Errorf("failed to write svg: %w", err)
+			}
+		}
+
+		if zw != nil {
+			// no modification time, so the zip only changes when the silhouettes do
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+			if err != nil {
+				return fmt.Errorf("failed to add %s to zip: %w", name, err)
+			}
+			if _, err := w.Write(clean); err != nil {
+				return fmt.Errorf("failed to add %s to zip: %w", name, err)
+			}
+		}
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("failed to write zip: %w", err)
+		}
+	}
+
+	log.Info().Int("files", len(files)).Int("before", before).Int("after", after).Msg("done")
+	return nil
+}
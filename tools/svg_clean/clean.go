@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/plane-watch/pw-silhouettes/internal/svgpath"
+)
+
+const (
+	svgNS   = "http://www.w3.org/2000/svg"
+	xlinkNS = "http://www.w3.org/1999/xlink"
+)
+
+// dropElements are SVG elements that never belong in a release-ready silhouette:
+// reference artwork, scripts, and descriptive metadata.
+var dropElements = map[string]bool{
+	"image":    true,
+	"script":   true,
+	"metadata": true,
+	"title":    true,
+	"desc":     true,
+}
+
+// geometryAttrs are attributes holding a single length, rounded like path coordinates.
+var geometryAttrs = map[string]bool{
+	"x": true, "y": true, "width": true, "height": true,
+	"cx": true, "cy": true, "r": true, "rx": true, "ry": true,
+	"x1": true, "y1": true, "x2": true, "y2": true,
+}
+
+// idRefRe matches references to elements by id: url(#id) and href="#id"
+var idRefRe = regexp.MustCompile(`(?:url\(\s*['"]?#|^\s*#)([^'")\s]+)`)
+
+// element is an SVG element being cleaned.
+type element struct {
+	Name     string
+	Attr     []xml.Attr
+	Children []*element
+	Text     string // character data, only kept for <style>
+}
+
+func (e *element) attr(name string) (string, bool) {
+	for _, a := range e.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func (e *element) setAttr(name, value string) {
+	for i, a := range e.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			e.Attr[i].Value = value
+			return
+		}
+	}
+	e.Attr = append(e.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+// Clean returns a minimal version of an SVG containing only the visible artwork. Hidden layers,
+// reference images, editor (Inkscape/Sodipodi) metadata, comments and unreferenced ids and <defs>
+// are removed, and coordinates are rounded to the given number of decimal places of a pixel.
+func Clean(data []byte, precision int) ([]byte, error) {
+	root, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// removing unreferenced content can leave more unreferenced, so repeat until nothing changes
+	for {
+		refs := referencedIDs(root)
+		stripIDs(root, refs)
+		if !prune(root, refs) {
+			break
+		}
+	}
+
+	width, _ := root.attr("width")
+	viewBox, _ := root.attr("viewBox")
+	scale := svgdoc.PixelsPerUnit(width, viewBox)
+	for _, c := range root.Children {
+		if err := round(c, scale, precision); err != nil {
+			return nil, err
+		}
+	}
+
+	ns := ` xmlns="` + svgNS + `"`
+	if usesXlink(root) {
+		ns += ` xmlns:xlink="` + xlinkNS + `"`
+	}
+	var buf bytes.Buffer
+	root.write(&buf, ns)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// parse builds the tree of visible SVG elements, dropping everything else on the way.
+func parse(data []byte) (*element, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	var (
+		root  *element
+		stack []*element
+		skip  int // depth within a dropped element
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xml parse error: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 || t.Name.Space != svgNS || dropElements[t.Name.Local] || svgdoc.Hidden(t.Attr) {
+				skip++
+				continue
+			}
+			e := &element{Name: t.Name.Local, Attr: cleanAttrs(t.Attr)}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("more than one root element")
+				}
+				root = e
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, e)
+			}
+			stack = append(stack, e)
+
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			if skip == 0 && len(stack) > 0 && stack[len(stack)-1].Name == "style" {
+				stack[len(stack)-1].Text += string(t)
+			}
+		}
+	}
+
+	if root == nil || root.Name != "svg" {
+		return nil, fmt.Errorf("no <svg> root element found")
+	}
+	return root, nil
+}
+
+// cleanAttrs drops attributes from editor namespaces and namespace declarations, and Inkscape's own
+// style properties. xlink:href is the only namespaced attribute kept.
+func cleanAttrs(attrs []xml.Attr) []xml.Attr {
+	out := make([]xml.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		switch {
+		case a.Name.Space == xlinkNS && a.Name.Local == "href":
+		case a.Name.Space != "":
+			continue
+		case a.Name.Local == "xmlns" || a.Name.Local == "version":
+			continue
+		case a.Name.Local == "style":
+			var keep []string
+			for _, d := range styleDecls(a.Value) {
+				if !strings.HasPrefix(d[0], "-inkscape-") {
+					keep = append(keep, d[0]+":"+d[1])
+				}
+			}
+			if len(keep) == 0 {
+				continue
+			}
+			a.Value = strings.Join(keep, ";")
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// styleDecls splits a style attribute into its property/value pairs, in order.
+func styleDecls(style string) [][2]string {
+	var out [][2]string
+	for _, decl := range strings.Split(style, ";") {
+		k, v, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		out = append(out, [2]string{strings.TrimSpace(k), strings.TrimSpace(v)})
+	}
+	return out
+}
+
+// referencedIDs returns the ids referred to anywhere in the tree.
+func referencedIDs(e *element) map[string]bool {
+	refs := map[string]bool{}
+	var walk func(*element)
+	walk = func(e *element) {
+		for _, a := range e.Attr {
+			for _, m := range idRefRe.FindAllStringSubmatch(a.Value, -1) {
+				refs[m[1]] = true
+			}
+		}
+		for _, m := range idRefRe.FindAllStringSubmatch(e.Text, -1) {
+			refs[m[1]] = true
+		}
+		for _, c := range e.Children {
+			walk(c)
+		}
+	}
+	walk(e)
+	return refs
+}
+
+// prune removes <defs> content that nothing refers to, and groups left empty.
+// Groups with no attributes at all are replaced by their children. It returns true if anything changed.
+func prune(e *element, refs map[string]bool) bool {
+	changed := false
+	var out []*element
+	for _, c := range e.Children {
+		if prune(c, refs) {
+			changed = true
+		}
+		switch {
+		case e.Name == "defs" && !containsRef(c, refs):
+			changed = true
+		case (c.Name == "g" || c.Name == "defs") && len(c.Children) == 0:
+			changed = true
+		case c.Name == "g" && len(c.Attr) == 0:
+			out = append(out, c.Children...)
+			changed = true
+		default:
+			out = append(out, c)
+		}
+	}
+	e.Children = out
+	return changed
+}
+
+// containsRef returns true if e, or anything within it, has a referenced id.
+func containsRef(e *element, refs map[string]bool) bool {
+	if id, ok := e.attr("id"); ok && refs[id] {
+		return true
+	}
+	for _, c := range e.Children {
+		if containsRef(c, refs) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripIDs removes ids that nothing refers to.
+func stripIDs(e *element, refs map[string]bool) {
+	out := e.Attr[:0]
+	for _, a := range e.Attr {
+		if a.Name.Space == "" && a.Name.Local == "id" && !refs[a.Value] {
+			continue
+		}
+		out = append(out, a)
+	}
+	e.Attr = out
+	for _, c := range e.Children {
+		stripIDs(c, refs)
+	}
+}
+
+// round rounds the coordinates of e and its children. scale is the number of pixels per user unit
+// of e's parent, so coordinates keep the same precision in pixels however they're transformed.
+func round(e *element, scale float64, precision int) error {
+	parentDecimals := svgpath.Decimals(scale, precision)
+
+	if v, ok := e.attr("transform"); ok {
+		m, err := svgpath.ParseTransform(v)
+		if err != nil {
+			return fmt.Errorf("<%s> invalid transform: %w", e.Name, err)
+		}
+		e.setAttr("transform", formatTransform(m, parentDecimals, precision))
+		scale *= m.Scale()
+	}
+	decimals := svgpath.Decimals(scale, precision)
+
+	for i, a := range e.Attr {
+		if a.Name.Space != "" {
+			continue
+		}
+		switch {
+		case a.Name.Local == "d" && e.Name == "path":
+			subpaths, err := svgpath.Parse(a.Value)
+			if err != nil {
+				return fmt.Errorf("<path> invalid path data: %w", err)
+			}
+			e.Attr[i].Value = svgpath.Format(subpaths, decimals)
+		case a.Name.Local == "points":
+			e.Attr[i].Value = roundNumbers(a.Value, decimals)
+		case geometryAttrs[a.Name.Local]:
+			if v, err := strconv.ParseFloat(strings.TrimSpace(a.Value), 64); err == nil {
+				e.Attr[i].Value = svgpath.FormatNumber(svgpath.Round(v, decimals), decimals)
+			}
+		}
+	}
+
+	for _, c := range e.Children {
+		if err := round(c, scale, precision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatTransform writes a transform as compactly as possible. Offsets are rounded like coordinates
+// in the parent, while the scale and rotation keep precision+3 significant figures.
+func formatTransform(m svgpath.Matrix, decimals, precision int) string {
+	sig := func(v float64) string {
+		if v == 0 {
+			return "0"
+		}
+		d := precision + 3 - int(math.Ceil(math.Log10(math.Abs(v))))
+		return svgpath.FormatNumber(svgpath.Round(v, d), d)
+	}
+	off := func(v float64) string {
+		return svgpath.FormatNumber(svgpath.Round(v, decimals), decimals)
+	}
+
+	switch {
+	case m.A == 1 && m.B == 0 && m.C == 0 && m.D == 1:
+		return "translate(" + off(m.E) + "," + off(m.F) + ")"
+	case m.B == 0 && m.C == 0 && m.E == 0 && m.F == 0:
+		return "scale(" + sig(m.A) + "," + sig(m.D) + ")"
+	}
+	return "matrix(" + strings.Join([]string{sig(m.A), sig(m.B), sig(m.C), sig(m.D), off(m.E), off(m.F)}, ",") + ")"
+}
+
+// roundNumbers rounds every number in a list such as a points attribute.
+func roundNumbers(s string, decimals int) string {
+	f := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' })
+	out := make([]string, 0, len(f))
+	for _, v := range f {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return s
+		}
+		out = append(out, svgpath.FormatNumber(svgpath.Round(n, decimals), decimals))
+	}
+	return strings.Join(out, " ")
+}
+
+func usesXlink(e *element) bool {
+	for _, a := range e.Attr {
+		if a.Name.Space == xlinkNS {
+			return true
+		}
+	}
+	for _, c := range e.Children {
+		if usesXlink(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// write serialises e without any whitespace between elements. ns holds any namespace declarations.
+func (e *element) write(buf *bytes.Buffer, ns string) {
+	buf.WriteString("<" + e.Name + ns)
+	for _, a := range e.Attr {
+		buf.WriteByte(' ')
+		if a.Name.Space == xlinkNS {
+			buf.WriteString("xlink:")
+		}
+		buf.WriteString(a.Name.Local + `="`)
+		xml.EscapeText(buf, []byte(a.Value))
+		buf.WriteByte('"')
+	}
+	if len(e.Children) == 0 && e.Text == "" {
+		buf.WriteString("/>")
+		return
+	}
+	buf.WriteByte('>')
+	xml.EscapeText(buf, []byte(e.Text))
+	for _, c := range e.Children {
+		c.write(buf, "")
+	}
+	buf.WriteString("</" + e.Name + ">")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:   "svg_clean",
+	Usage:  "Write minimal, release-ready SVGs containing only the visible outline",
+	Action: runApp,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "input",
+			Usage: "SVG file, or directory of SVG files, to clean",
+			Value: []string{"silhouettes/"},
+		},
+		&cli.StringFlag{
+			Name:  "output_dir",
+			Usage: "Write cleaned SVGs to this directory",
+		},
+		&cli.StringFlag{
+			Name:  "output_zip",
+			Usage: "Write cleaned SVGs to this zip file",
+		},
+		&cli.IntFlag{
+			Name:  "precision",
+			Usage: "Number of decimal places of a pixel to keep in coordinates",
+			Value: 2,
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/inkscape"
+	"github.com/plane-watch/pw-silhouettes/internal/raster"
+	"github.com/plane-watch/pw-silhouettes/internal/refimage"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+type options struct {
+	InkscapeBinary string
+	MinIoU         float64
+	OverlayDir     string
+	Zoom           int
+	Threshold      float64
+	Close          float64
+	Open           float64
+}
+
+// score is how closely a silhouette follows its reference artwork.
+type score struct {
+	File        string
+	IoU         float64 // intersection-over-union of the outline and the reference's shape
+	MaxDistance float64 // furthest either contour gets from the other, in px
+	Overlay     *image.NRGBA
+}
+
+func runApp(_ context.Context, cmd *cli.Command) error {
+
+	opts := options{
+		InkscapeBinary: cmd.String("inkscape_binary"),
+		MinIoU:         cmd.Float("min_iou"),
+		OverlayDir:     cmd.String("overlay_dir"),
+		Zoom:           int(cmd.Int("zoom")),
+		Threshold:      cmd.Float("threshold"),
+		Close:          cmd.Float("close"),
+		Open:           cmd.Float("open"),
+	}
+	if opts.Zoom < 1 {
+		return fmt.Errorf("zoom must be >= 1")
+	}
+
+	files, err := svgdoc.Files(cmd.StringSlice("input"))
+	if err != nil {
+		return err
+	}
+
+	var scores []*score
+	skipped := 0
+	for _, file := range files {
+		s, err := scoreFile(file, opts)
+		if err != nil {
+			return fmt.Errorf("failed to score %s: %w", file, err)
+		}
+		if s == nil {
+			skipped++
+			continue
+		}
+		log.Info().
+			Str("file", file).
+			Float64("iou", s.IoU).
+			Float64("max_distance_px", s.MaxDistance).
+			Msg("scored silhouette")
+		scores = append(scores, s)
+	}
+
+	// list the worst first
+	var below []*score
+	for _, s := range scores {
+		if s.IoU < opts.MinIoU {
+			below = append(below, s)
+		}
+	}
+	slices.SortFunc(below, func(a, b *score) int {
+		if a.IoU != b.IoU {
+			if a.IoU < b.IoU {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.File, b.File)
+	})
+
+	if len(below) > 0 {
+		if err := os.MkdirAll(opts.OverlayDir, 0755); err != nil {
+			return fmt.Errorf("failed to create overlay dir: %w", err)
+		}
+	}
+	for _, s := range below {
+		name := strings.TrimSuffix(filepath.Base(s.File), filepath.Ext(s.File)) + "-overlay.png"
+		overlay := filepath.Join(opts.OverlayDir, name)
+		if err := raster.WritePNG(overlay, s.Overlay); err != nil {
+			return err
+		}
+		log.Error().
+			Str("file", s.File).
+			Float64("iou", s.IoU).
+			Float64("max_distance_px", s.MaxDistance).
+			Str("overlay", overlay).
+			Msgf("outline doesn't follow the reference artwork (iou below %g)", opts.MinIoU)
+	}
+
+	log.Info().
+		Int("files", len(files)).
+		Int("scored", len(scores)).
+		Int("skipped", skipped).
+		Int("below_min_iou", len(below)).
+		Msg("done")
+
+	if len(below) > 0 {
+		return fmt.Errorf("%d silhouettes below --min_iou %g", len(below), opts.MinIoU)
+	}
+	return nil
+}
+
+// scoreFile compares a silhouette's rendered outline with the shape of its reference artwork. It
+// returns nil if the silhouette has no reference artwork, or it's in a format that can't be read.
+func scoreFile(file string, opts options) (*score, error) {
+	ref, err := refimage.Load(file)
+	if errors.Is(err, refimage.ErrNoReference) {
+		// silhouettes without reference artwork can't be scored, but don't stop the rest
+		log.Warn().Str("file", file).Msg("skipping silhouette without reference artwork")
+		return nil, nil
+	}
+	if errors.Is(err, refimage.ErrUnsupportedReference) {
+		log.Warn().Str("file", file).Err(err).Msg("skipping silhouette with reference artwork that can't be read")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reference image: %w", err)
+	}
+
+	px := func(v float64) int { return int(v*float64(opts.Zoom) + 0.5) }
+	grid := ref.Resample(opts.Zoom)
+	reference := refimage.Silhouette(grid, opts.Threshold, px(opts.Close), px(opts.Open))
+	if reference.Count() == 0 {
+		log.Warn().Str("file", file).Int("line", ref.Line).Msg("skipping silhouette, as nothing stands out from the background of its reference artwork (try a lower --threshold)")
+		return nil, nil
+	}
+
+	img, err := inkscape.Render(opts.InkscapeBinary, file, grid.Rect.Dx())
+	if err != nil {
+		return nil, err
+	}
+	outline := raster.MaskFrom(img)
+	if outline.W != reference.W || outline.H != reference.H {
+		return nil, fmt.Errorf("rendered outline is %dx%d, expected %dx%d", outline.W, outline.H, reference.W, reference.H)
+	}
+
+	if outline.Count() == 0 {
+		log.Warn().Str("file", file).Msg("skipping silhouette with nothing visible")
+		return nil, nil
+	}
+
+	s := &score{
+		File:    file,
+		IoU:     float64(outline.And(reference).Count()) / float64(outline.Or(reference).Count()),
+		Overlay: overlayImage(grid, outline, reference),
+	}
+	s.MaxDistance = contourDistance(outline, reference) / float64(opts.Zoom)
+	return s, nil
+}
+
+// contourDistance returns the Hausdorff distance between the edges of two shapes: the furthest any
+// point on either edge is from the nearest point on the other.
+func contourDistance(a, b *raster.Mask) float64 {
+	ea, eb := a.Edges(), b.Edges()
+	da, db := ea.Distances(), eb.Distances()
+	var worst float64
+	for i := range ea.On {
+		if ea.On[i] {
+			worst = max(worst, db[i])
+		}
+		if eb.On[i] {
+			worst = max(worst, da[i])
+		}
+	}
+	return worst
+}
+
+// overlayImage shows where the outline and the reference artwork disagree, over a faded copy of the
+// reference: pixels only in the outline are red, pixels only in the reference's shape are blue, and
+// pixels in both are shaded grey.
+func overlayImage(grid *image.NRGBA, outline, reference *raster.Mask) *image.NRGBA {
+	img := image.NewNRGBA(grid.Rect)
+	fade := func(v uint8) uint8 { return 0xff - (0xff-v)/3 }
+	shade := func(v uint8) uint8 { return uint8(int(v) * 4 / 5) }
+	for y := 0; y < outline.H; y++ {
+		for x := 0; x < outline.W; x++ {
+			c := grid.NRGBAAt(x, y)
+			c = color.NRGBA{R: fade(c.R), G: fade(c.G), B: fade(c.B), A: 0xff}
+			switch inO, inR := outline.At(x, y), reference.At(x, y); {
+			case inO && inR:
+				c = color.NRGBA{R: shade(c.R), G: shade(c.G), B: shade(c.B), A: 0xff}
+			case inO:
+				c = color.NRGBA{R: 0xff, A: 0xff}
+			case inR:
+				c = color.NRGBA{B: 0xff, A: 0xff}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:   "svg_fidelity",
+	Usage:  "Score how closely each silhouette's outline follows its hidden reference artwork",
+	Action: runApp,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "input",
+			Usage: "SVG file, or directory of SVG files, to score",
+			Value: []string{"silhouettes/"},
+		},
+		&cli.StringFlag{
+			Name:     "inkscape_binary",
+			Aliases:  []string{"inkscape"},
+			Usage:    "Path to the inkscape v1+ binary, used to render the outline",
+			Required: true,
+		},
+		&cli.FloatFlag{
+			Name:  "min_iou",
+			Usage: "List silhouettes whose intersection-over-union with the reference artwork is below this, from 0 to 1",
+			Value: 0.8,
+		},
+		&cli.StringFlag{
+			Name:  "overlay_dir",
+			Usage: "Directory to write an overlay PNG to for each silhouette listed",
+			Value: "fidelity/",
+		},
+		&cli.IntFlag{
+			Name:  "zoom",
+			Usage: "Compare at this multiple of the 70px canvas, for sub-pixel precision",
+			Value: 8,
+		},
+		&cli.FloatFlag{
+			Name:  "threshold",
+			Usage: "How far a colour must be from the reference image's background to count as part of the aircraft, from 0 to 1",
+			Value: 0.15,
+		},
+		&cli.FloatFlag{
+			Name:  "close",
+			Usage: "Gaps in the reference artwork up to this many px across are closed",
+			Value: 1,
+		},
+		&cli.FloatFlag{
+			Name:  "open",
+			Usage: "Parts of the reference artwork's shape up to this many px across, such as dimension lines touching it, are ignored",
+			Value: 0.25,
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
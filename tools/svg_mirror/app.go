@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/plane-watch/pw-silhouettes/internal/svgpath"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// outlinePath is a visible <path> found in the SVG.
+type outlinePath struct {
+	ID    string
+	Line  int
+	Start int64  // where its start tag begins
+	Raw   []byte // the raw start tag
+	D     string
+	CTM   svgpath.Matrix // from its user units to the root's user units
+}
+
+// document is what we need to know about the SVG.
+type document struct {
+	PixelsPerUnit float64 // of the root's user units
+	ViewBoxX      float64
+	Paths         []outlinePath
+}
+
+func runApp(_ context.Context, cmd *cli.Command) error {
+
+	file := cmd.String("svg")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read svg: %w", err)
+	}
+	doc, err := scanDocument(data)
+	if err != nil {
+		return fmt.Errorf("invalid svg file: %w", err)
+	}
+	// the outline is styled as svg_check will check the file it's written to
+	target := cmd.String("output")
+	if target == "" {
+		target = file
+	}
+	profile, err := svgcheck.ProfileForSVG(cmd.String("config"), cmd.String("profile"), target)
+	if err != nil {
+		return err
+	}
+
+	var p outlinePath
+	if id := cmd.String("path_id"); id != "" {
+		i := indexOf(doc.Paths, id)
+		if i < 0 {
+			return fmt.Errorf("no visible path with id %q", id)
+		}
+		p = doc.Paths[i]
+	} else {
+		if len(doc.Paths) != 1 {
+			return fmt.Errorf("found %d visible paths, use --path_id to choose the half outline", len(doc.Paths))
+		}
+		p = doc.Paths[0]
+	}
+
+	// mirroring is done in the path's own user units, which only works if they aren't rotated or skewed
+	m := p.CTM
+	if math.Abs(m.B) > 1e-9 || math.Abs(m.C) > 1e-9 {
+		return fmt.Errorf("line %d: the path is rotated or skewed by a transform, which must be removed first", p.Line)
+	}
+	pxPerUnit := doc.PixelsPerUnit * math.Abs(m.A)
+	centre := (doc.ViewBoxX + cmd.Float("centre_x")/doc.PixelsPerUnit - m.E) / m.A
+
+	subpaths, err := svgpath.Parse(p.D)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid path data: %w", p.Line, err)
+	}
+	if len(subpaths) != 1 {
+		return fmt.Errorf("line %d: the half outline must be a single subpath (found %d)", p.Line, len(subpaths))
+	}
+
+	whole, err := mirrorHalf(subpaths[0], centre, cmd.Float("snap")/pxPerUnit, pxPerUnit)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", p.Line, err)
+	}
+	d := svgpath.Format([]svgpath.Subpath{whole}, svgpath.Decimals(pxPerUnit, int(cmd.Int("precision"))))
+
+	out := svgdoc.Apply(data, p.edits(d, profile.OutlineStyle()))
+
+	dst := cmd.String("output")
+	if dst == "" {
+		if err := svgdoc.WriteFileAtomic(file, out); err != nil {
+			return fmt.Errorf("failed to write svg: %w", err)
+		}
+		dst = file
+	} else if err := os.WriteFile(dst, out, 0644); err != nil {
+		return fmt.Errorf("failed to write svg: %w", err)
+	}
+
+	log.Info().
+		Str("file", dst).
+		Int("half_nodes", subpaths[0].Nodes()).
+		Int("nodes", whole.Nodes()).
+		Msg("mirrored outline")
+	return nil
+}
+
+func indexOf(paths []outlinePath, id string) int {
+	for i, p := range paths {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// scanDocument finds the root's coordinate system, and the visible paths.
+func scanDocument(data []byte) (*document, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	type frame struct {
+		hidden bool
+		ctm    svgpath.Matrix
+	}
+	stack := []frame{{ctm: svgpath.Identity}}
+	doc := &document{PixelsPerUnit: 1}
+
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xml parse error: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			line, _ := dec.InputPos()
+			parent := stack[len(stack)-1]
+			f := frame{
+				hidden: parent.hidden || svgdoc.Hidden(t.Attr) || t.Name.Local == "defs",
+				ctm:    parent.ctm,
+			}
+
+			attrs := map[string]string{}
+			for _, a := range t.Attr {
+				if a.Name.Space == "" {
+					attrs[a.Name.Local] = a.Value
+				}
+			}
+
+			if len(stack) == 1 {
+				// the root's transform from viewBox to px is accounted for separately
+				doc.PixelsPerUnit = svgdoc.PixelsPerUnit(attrs["width"], attrs["viewBox"])
+				if vb := strings.Fields(strings.ReplaceAll(attrs["viewBox"], ",", " ")); len(vb) == 4 {
+					doc.ViewBoxX, _ = strconv.ParseFloat(vb[0], 64)
+				}
+			} else if v, ok := attrs["transform"]; ok {
+				m, err := svgpath.ParseTransform(v)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: <%s> invalid transform: %w", line, t.Name.Local, err)
+				}
+				f.ctm = f.ctm.Mul(m)
+			}
+
+			if d, ok := attrs["d"]; ok && t.Name.Local == "path" && !f.hidden {
+				doc.Paths = append(doc.Paths, outlinePath{
+					ID:    attrs["id"],
+					Line:  line,
+					Start: start,
+					Raw:   data[start:dec.InputOffset()],
+					D:     d,
+					CTM:   f.ctm,
+				})
+			}
+			stack = append(stack, f)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return doc, nil
+}
+
+// edits returns the edits that replace the path's data and set the style. Inkscape's record of its
+// node types is dropped, as it would no longer match.
+func (p outlinePath) edits(d string, style map[string]string) []svgdoc.Edit {
+	attrs := svgdoc.ScanRawAttrs(p.Raw)
+	at := func(a svgdoc.RawAttr, text string) svgdoc.Edit {
+		var buf bytes.Buffer
+		xml.EscapeText(&buf, []byte(text))
+		return svgdoc.Edit{Start: p.Start + int64(a.ValueStart), End: p.Start + int64(a.ValueEnd), Text: buf.Bytes()}
+	}
+
+	edits := []svgdoc.Edit{at(attrs["d"], d)}
+
+	if a, ok := attrs["style"]; ok {
+		edits = append(edits, at(a, svgdoc.SetStyleProperties(a.Value, svgcheck.OutlineStyleKeys, style)))
+	} else {
+		// no style attribute: add one just before the end of the tag
+		end := len(p.Raw) - 1
+		if end > 0 && p.Raw[end-1] == '/' {
+			end--
+		}
+		attr := svgdoc.SetStyleProperties("", svgcheck.OutlineStyleKeys, style)
+		edits = append(edits, svgdoc.Edit{Start: p.Start + int64(end), End: p.Start + int64(end), Text: []byte(` style="` + attr + `"`)})
+	}
+
+	for name, a := range attrs {
+		// presentation attributes would be overridden by the style anyway
+		if _, ok := style[name]; ok || strings.HasSuffix(name, ":nodetypes") {
+			edits = append(edits, svgdoc.RemoveAttr(p.Raw, p.Start, a))
+		}
+	}
+	return edits
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:   "svg_mirror",
+	Usage:  "Complete a half-traced outline by mirroring it across the centreline and joining the two halves",
+	Action: runApp,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "svg",
+			Usage:    "Path to the SVG file containing the half outline",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "Write the result to this file, instead of replacing --svg",
+		},
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "Path to the svg_check config file, whose profile sets the outline's style (default: the first svg_check.json in the svg's directory or above it)",
+		},
+		&cli.StringFlag{
+			Name:  "profile",
+			Usage: "Name of the svg_check profile to style the outline with (default: chosen by the config file)",
+		},
+		&cli.StringFlag{
+			Name:  "path_id",
+			Usage: "id of the half outline path (default: the only visible path)",
+		},
+		&cli.FloatFlag{
+			Name:  "centre_x",
+			Usage: "x-coordinate of the centreline, in px from the left of the 70px canvas",
+			Value: 35,
+		},
+		&cli.FloatFlag{
+			Name:  "snap",
+			Usage: "Maximum distance in px that the ends of the half outline may be from the centreline; they are moved onto it",
+			Value: 0.5,
+		},
+		&cli.IntFlag{
+			Name:  "precision",
+			Usage: "Decimal places of a pixel to keep in the written path data",
+			Value: 3,
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgpath"
+)
+
+// mirrorHalf joins a half outline to its mirror image across the vertical line x = c, returning
+// the closed outline. The half must either be open with both ends within snap of the line, or be
+// closed by a straight line along it. The ends are moved onto the line so the halves meet exactly.
+// pxPerUnit is only used to report distances in px.
+func mirrorHalf(sp svgpath.Subpath, c, snap, pxPerUnit float64) (svgpath.Subpath, error) {
+	onAxis := func(p svgpath.Point) bool { return math.Abs(p.X-c) <= snap }
+	isSeam := func(s svgpath.Segment) bool { return s.Cmd == 'L' && onAxis(s.Start) && onAxis(s.End) }
+
+	var segs []svgpath.Segment
+	for _, s := range sp.Segments {
+		if !s.ZeroLength() {
+			segs = append(segs, s)
+		}
+	}
+	if len(segs) == 0 {
+		return svgpath.Subpath{}, fmt.Errorf("the half outline has no segments")
+	}
+
+	if sp.Closed {
+		if last := segs[len(segs)-1]; !last.End.Near(sp.Start) {
+			segs = append(segs, svgpath.Segment{Cmd: 'L', Start: last.End, End: sp.Start})
+		}
+		// open it up along the centreline, starting just after the seam
+		i := slices.IndexFunc(segs, isSeam)
+		if i < 0 {
+			return svgpath.Subpath{}, fmt.Errorf("the half outline is closed, but not by a straight line along the centreline")
+		}
+		segs = append(segs[i+1:], segs[:i]...)
+	}
+
+	// a seam may have been drawn as several lines
+	for len(segs) > 0 && isSeam(segs[0]) {
+		segs = segs[1:]
+	}
+	for len(segs) > 0 && isSeam(segs[len(segs)-1]) {
+		segs = segs[:len(segs)-1]
+	}
+	if len(segs) == 0 {
+		return svgpath.Subpath{}, fmt.Errorf("the half outline lies entirely on the centreline")
+	}
+
+	first, last := &segs[0], &segs[len(segs)-1]
+	if !onAxis(first.Start) {
+		return svgpath.Subpath{}, fmt.Errorf("the start of the half outline is %.2fpx from the centreline (more than --snap)", math.Abs(first.Start.X-c)*pxPerUnit)
+	}
+	if !onAxis(last.End) {
+		return svgpath.Subpath{}, fmt.Errorf("the end of the half outline is %.2fpx from the centreline (more than --snap)", math.Abs(last.End.X-c)*pxPerUnit)
+	}
+	first.Start.X = c
+	last.End.X = c
+	if first.Start.Near(last.End) {
+		return svgpath.Subpath{}, fmt.Errorf("the half outline starts and ends at the same point")
+	}
+
+	// the mirror image runs back from the end to the start
+	out := slices.Clone(segs)
+	for i := len(segs) - 1; i >= 0; i-- {
+		out = append(out, mirrorSegment(segs[i], c))
+	}
+	out = mergeOnAxis(out, c)
+
+	return svgpath.Subpath{Start: out[0].Start, Segments: out, Closed: true}, nil
+}
+
+// mirrorSegment reflects a segment across x = c, and reverses it.
+func mirrorSegment(s svgpath.Segment, c float64) svgpath.Segment {
+	r := func(p svgpath.Point) svgpath.Point { return svgpath.Point{X: 2*c - p.X, Y: p.Y} }
+
+	m := s
+	m.Start, m.End = r(s.End), r(s.Start)
+	m.Ctrl = nil
+	for i := len(s.Ctrl) - 1; i >= 0; i-- {
+		m.Ctrl = append(m.Ctrl, r(s.Ctrl[i]))
+	}
+	// reflecting an arc reverses its direction, and so does reversing it, so the sweep is unchanged
+	m.Rotation = -s.Rotation
+	return m
+}
+
+// mergeOnAxis joins straight lines that meet on the centreline and continue in the same direction
+// (such as a tail drawn square across the centreline), so no node is left where the halves meet.
+// The segments form a closed loop.
+func mergeOnAxis(segs []svgpath.Segment, c float64) []svgpath.Segment {
+	for i := 0; len(segs) > 2 && i < len(segs); {
+		j := (i + 1) % len(segs)
+		a, b := segs[i], segs[j]
+		if a.Cmd != 'L' || b.Cmd != 'L' || a.End.X != c || !collinear(a, b) {
+			i++
+			continue
+		}
+		segs[i] = svgpath.Segment{Cmd: 'L', Start: a.Start, End: b.End}
+		segs = slices.Delete(segs, j, j+1)
+	}
+	return segs
+}
+
+func collinear(a, b svgpath.Segment) bool {
+	ax, ay := a.End.X-a.Start.X, a.End.Y-a.Start.Y
+	bx, by := b.End.X-b.Start.X, b.End.Y-b.Start.Y
+	la, lb := math.Hypot(ax, ay), math.Hypot(bx, by)
+	if la == 0 || lb == 0 {
+		return false
+	}
+	cross := (ax*by - ay*bx) / (la * lb)
+	dot := (ax*bx + ay*by) / (la * lb)
+	return math.Abs(cross) < 1e-9 && dot > 0
+}
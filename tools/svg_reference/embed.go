@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+func runEmbed(_ context.Context, cmd *cli.Command) error {
+
+	files, err := svgdoc.Files(cmd.StringSlice("input"))
+	if err != nil {
+		return err
+	}
+
+	var embedded int
+	for _, file := range files {
+		n, err := embedFile(file, cmd.String("reference_dir"))
+		if err != nil {
+			return fmt.Errorf("failed to embed reference images into %s: %w", file, err)
+		}
+		embedded += n
+	}
+
+	log.Info().Int("files", len(files)).Int("images", embedded).Msg("done")
+	return nil
+}
+
+// embedFile puts the reference images of one SVG back into it, returning how many were embedded.
+// Linked images are replaced with their content, and images that were dropped are restored into
+// the Reference Artwork layer.
+func embedFile(file, referenceDir string) (int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read svg: %w", err)
+	}
+	res, err := scanSVG(data)
+	if err != nil {
+		return 0, err
+	}
+
+	var edits []svgdoc.Edit
+	linked := map[string]bool{}   // absolute paths of the images the SVG links to
+	embedded := map[string]bool{} // sha256 of the images already embedded in the SVG
+
+	for _, img := range res.Images {
+		if strings.HasPrefix(strings.TrimSpace(img.Href), "data:") {
+			if _, content, err := svgdoc.DecodeDataURL(img.Href); err == nil {
+				sum := sha256.Sum256(content)
+				embedded[hex.EncodeToString(sum[:])] = true
+			}
+			continue
+		}
+		if _, ok := svgdoc.ReferenceLink(img.Href); !ok {
+			continue
+		}
+		path, dataURL, err := readLinked(file, img.Href)
+		if err != nil {
+			return 0, err
+		}
+		linked[path] = true
+		edits = append(edits, img.replaceHref(dataURL))
+	}
+
+	st, err := loadStore(referenceDir, designatorOf(file))
+	if err != nil {
+		return 0, err
+	}
+
+	var restored bytes.Buffer
+	for _, sc := range st.Sidecars {
+		imagePath, err := filepath.Abs(filepath.Join(st.Dir, sc.Image))
+		if err != nil {
+			return 0, err
+		}
+		if linked[imagePath] || embedded[sc.SHA256] {
+			continue
+		}
+		for _, u := range sc.UsedBy {
+			if filepath.Base(u.SVG) != filepath.Base(file) {
+				continue
+			}
+			element, err := embedElement(file, u.Element)
+			if err != nil {
+				return 0, fmt.Errorf("failed to restore %s: %w", sc.Image, err)
+			}
+			restored.WriteString(element)
+		}
+	}
+
+	if restored.Len() > 0 {
+		switch {
+		case res.ReferenceLayer == nil:
+			log.Warn().Str("file", file).Msg("no Reference Artwork layer to restore dropped reference images into")
+		case bytes.HasSuffix(res.ReferenceLayer, []byte("/>")):
+			// the layer is empty and self-closing, so open it up
+			edits = append(edits, svgdoc.Edit{
+				Start: res.ReferenceLayerEnd - 2,
+				End:   res.ReferenceLayerEnd,
+				Text:  append(append([]byte(">"), restored.Bytes()...), "</g>"...),
+			})
+		default:
+			edits = append(edits, svgdoc.Edit{Start: res.ReferenceLayerEnd, End: res.ReferenceLayerEnd, Text: restored.Bytes()})
+		}
+	}
+
+	if len(edits) == 0 {
+		return 0, nil
+	}
+	if err := svgdoc.WriteFileAtomic(file, svgdoc.Apply(data, edits)); err != nil {
+		return 0, fmt.Errorf("failed to write svg: %w", err)
+	}
+	log.Info().Str("file", file).Int("images", len(edits)).Msg("embedded reference images")
+	return len(edits), nil
+}
+
+// readLinked reads an image linked from an SVG, returning its absolute path and content as a data: URL.
+// Only links into the reference directory are followed.
+func readLinked(svgPath, href string) (string, string, error) {
+	rel, ok := svgdoc.ReferenceLink(href)
+	if !ok {
+		return "", "", fmt.Errorf("link %q is not to an image in %s/", href, svgdoc.ReferenceDir)
+	}
+	path, err := filepath.Abs(filepath.Join(filepath.Dir(svgPath), rel))
+	if err != nil {
+		return "", "", err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read reference image: %w", err)
+	}
+	dataURL, err := encodeDataURL(path, content)
+	if err != nil {
+		return "", "", err
+	}
+	return path, dataURL, nil
+}
+
+// embedElement returns an <image> element recorded in a sidecar, with its link replaced by the image content.
+func embedElement(svgPath, element string) (string, error) {
+	// the element is recorded with the whitespace before it
+	indent := len(element) - len(strings.TrimLeft(element, " \t\r\n"))
+
+	attrs := svgdoc.ScanRawAttrs([]byte(element[indent:]))
+	href, ok := attrs[rawPrefixed(attrs, "href")]
+	if !ok {
+		return "", fmt.Errorf("recorded element has no href")
+	}
+	_, dataURL, err := readLinked(svgPath, href.Value)
+	if err != nil {
+		return "", err
+	}
+	img := imageElement{Start: int64(indent), RawHref: href}
+	return string(svgdoc.Apply([]byte(element), []svgdoc.Edit{img.replaceHref(dataURL)})), nil
+}
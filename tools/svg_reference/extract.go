@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+func runExtract(_ context.Context, cmd *cli.Command) error {
+
+	mode := cmd.String("mode")
+	if mode != "link" && mode != "drop" {
+		return fmt.Errorf(`--mode must be "link" or "drop", not %q`, mode)
+	}
+	sourceURL := cmd.String("source_url")
+	licence := cmd.String("licence")
+
+	files, err := svgdoc.Files(cmd.StringSlice("input"))
+	if err != nil {
+		return err
+	}
+
+	var extracted int
+	for _, file := range files {
+		n, err := extractFile(file, cmd.String("reference_dir"), mode, sourceURL, licence)
+		if err != nil {
+			return fmt.Errorf("failed to extract reference images from %s: %w", file, err)
+		}
+		extracted += n
+	}
+
+	log.Info().Int("files", len(files)).Int("images", extracted).Msg("done")
+	return nil
+}
+
+// extractFile moves the embedded reference images of one SVG into the store, returning how many were moved.
+func extractFile(file, referenceDir, mode, sourceURL, licence string) (int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read svg: %w", err)
+	}
+	res, err := scanSVG(data)
+	if err != nil {
+		return 0, err
+	}
+
+	st, err := loadStore(referenceDir, designatorOf(file))
+	if err != nil {
+		return 0, err
+	}
+
+	// svg_check only accepts links into the reference directory beside the silhouettes, so check
+	// the store is there before moving anything into it
+	dir, err := filepath.Rel(filepath.Dir(file), st.Dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to link to reference dir: %w", err)
+	}
+	if want := svgdoc.ReferenceDir + "/" + designatorOf(file); filepath.ToSlash(dir) != want {
+		return 0, fmt.Errorf("reference images must be stored in %s relative to the svg, as svg_check rejects other links (got %s)", want, filepath.ToSlash(dir))
+	}
+
+	var edits []svgdoc.Edit
+	for _, img := range res.Images {
+		if !strings.HasPrefix(strings.TrimSpace(img.Href), "data:") {
+			continue
+		}
+		if !img.Hidden {
+			// visible images aren't reference artwork, and svg_check will reject them anyway
+			log.Warn().Str("file", file).Msg("skipping visible embedded image")
+			continue
+		}
+
+		mimeType, content, err := svgdoc.DecodeDataURL(img.Href)
+		if err != nil {
+			return 0, err
+		}
+		ext, ok := mimeTypes[mimeType]
+		if !ok {
+			log.Warn().Str("file", file).Str("type", mimeType).Msg("skipping embedded image of unknown type")
+			continue
+		}
+
+		sc, err := st.add(file, ext, content)
+		if err != nil {
+			return 0, err
+		}
+		st.setSource(sc, sourceURL, licence)
+
+		rel, err := filepath.Rel(filepath.Dir(file), filepath.Join(st.Dir, sc.Image))
+		if err != nil {
+			return 0, fmt.Errorf("failed to link to reference image: %w", err)
+		}
+		link := img.replaceHref(filepath.ToSlash(rel))
+
+		// the element as it would be with the link, with the whitespace that indents it,
+		// so it can be restored exactly after being dropped
+		start := img.Start
+		for start > 0 && strings.IndexByte(" \t\r\n", data[start-1]) >= 0 {
+			start--
+		}
+		element := string(svgdoc.Apply(data[start:img.End], []svgdoc.Edit{{
+			Start: link.Start - start,
+			End:   link.End - start,
+			Text:  link.Text,
+		}}))
+		st.use(sc, file, element)
+
+		switch mode {
+		case "link":
+			edits = append(edits, link)
+		case "drop":
+			edits = append(edits, svgdoc.Edit{Start: start, End: img.End})
+		}
+
+		if sc.SourceURL == "" || sc.Licence == "" {
+			log.Warn().Str("file", file).Str("image", sc.Image).Msg("reference image has no source URL or licence recorded")
+		}
+		log.Info().Str("file", file).Str("image", filepath.Join(st.Dir, sc.Image)).Msg("extracted reference image")
+	}
+
+	if err := st.save(); err != nil {
+		return 0, err
+	}
+	if len(edits) == 0 {
+		return 0, nil
+	}
+	if err := svgdoc.WriteFileAtomic(file, svgdoc.Apply(data, edits)); err != nil {
+		return 0, fmt.Errorf("failed to write svg: %w", err)
+	}
+	return len(edits), nil
+}
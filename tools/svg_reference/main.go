@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:  "svg_reference",
+	Usage: "Move reference artwork between silhouette SVGs and the reference/ provenance store",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "input",
+			Usage: "SVG file, or directory of SVG files, to process",
+			Value: []string{"silhouettes/"},
+		},
+		&cli.StringFlag{
+			Name:  "reference_dir",
+			Usage: "Path to the reference artwork store",
+			Value: "reference/",
+		},
+	},
+	Commands: []*cli.Command{
+		{
+			Name:   "extract",
+			Usage:  "Move embedded reference images into reference/<designator>/, with a sidecar recording where they came from",
+			Action: runExtract,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "mode",
+					Usage: `What to leave in the SVG: "link" (a relative link to the extracted image) or "drop" (nothing)`,
+					Value: "link",
+				},
+				&cli.StringFlag{
+					Name:  "source_url",
+					Usage: "URL the reference images were sourced from, recorded in their sidecars",
+				},
+				&cli.StringFlag{
+					Name:  "licence",
+					Usage: "Licence of the reference images, recorded in their sidecars",
+				},
+			},
+		},
+		{
+			Name:   "embed",
+			Usage:  "Re-embed extracted reference images into their SVGs, for retracing",
+			Action: runEmbed,
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
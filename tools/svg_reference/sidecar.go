@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sidecar records the provenance of an extracted reference image. It is stored alongside the image,
+// with the same name and a .json extension.
+type Sidecar struct {
+	Image     string  `json:"image"`     // filename of the image, in the same directory
+	SourceURL string  `json:"sourceUrl"` // where the image was sourced from
+	Licence   string  `json:"licence"`   // licence the image is available under
+	SHA256    string  `json:"sha256"`    // of the image, to find images shared by several silhouettes
+	UsedBy    []Usage `json:"usedBy"`    // silhouettes traced from the image
+}
+
+// Usage is a silhouette the image was extracted from.
+type Usage struct {
+	SVG string `json:"svg"`
+
+	// Element is the <image> as it was in the silhouette (linking to the extracted image),
+	// so it can be put back if it was dropped.
+	Element string `json:"element"`
+}
+
+// store is the reference images for one designator.
+type store struct {
+	Dir      string
+	Sidecars []*Sidecar
+	dirty    map[*Sidecar]bool
+}
+
+// loadStore reads the sidecars for a designator from the reference dir.
+func loadStore(referenceDir, designator string) (*store, error) {
+	s := &store{Dir: filepath.Join(referenceDir, designator), dirty: map[*Sidecar]bool{}}
+
+	matches, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range matches {
+		b, err := os.ReadFile(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sidecar: %w", err)
+		}
+		sc := &Sidecar{}
+		if err := json.Unmarshal(b, sc); err != nil {
+			return nil, fmt.Errorf("failed to parse sidecar %s: %w", m, err)
+		}
+		s.Sidecars = append(s.Sidecars, sc)
+	}
+	return s, nil
+}
+
+// add stores an image extracted from an SVG, returning its sidecar. Images already in the store
+// (such as the same reference shared by every frame of an animation) are only stored once.
+func (s *store) add(svgPath string, ext string, data []byte) (*Sidecar, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if i := slices.IndexFunc(s.Sidecars, func(sc *Sidecar) bool { return sc.SHA256 == hash }); i >= 0 {
+		return s.Sidecars[i], nil
+	}
+
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create reference dir: %w", err)
+	}
+
+	// name the image after the silhouette, adding a number if that's taken
+	stem := strings.TrimSuffix(filepath.Base(svgPath), filepath.Ext(svgPath))
+	name := stem
+	for n := 2; s.taken(name); n++ {
+		name = stem + "_" + strconv.Itoa(n)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.Dir, name+ext), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write reference image: %w", err)
+	}
+
+	sc := &Sidecar{Image: name + ext, SHA256: hash}
+	s.Sidecars = append(s.Sidecars, sc)
+	s.dirty[sc] = true
+	return sc, nil
+}
+
+// taken returns true if an image or sidecar already uses the name (without extension).
+func (s *store) taken(name string) bool {
+	matches, _ := filepath.Glob(filepath.Join(s.Dir, name+".*"))
+	return len(matches) > 0
+}
+
+// use records that an SVG used the image, and how.
+func (s *store) use(sc *Sidecar, svgPath string, element string) {
+	svgPath = filepath.ToSlash(filepath.Clean(svgPath))
+	for i, u := range sc.UsedBy {
+		if u.SVG == svgPath {
+			sc.UsedBy[i].Element = element
+			s.dirty[sc] = true
+			return
+		}
+	}
+	sc.UsedBy = append(sc.UsedBy, Usage{SVG: svgPath, Element: element})
+	s.dirty[sc] = true
+}
+
+// setSource records where an image came from, if given.
+func (s *store) setSource(sc *Sidecar, sourceURL, licence string) {
+	if sourceURL != "" && sc.SourceURL != sourceURL {
+		sc.SourceURL = sourceURL
+		s.dirty[sc] = true
+	}
+	if licence != "" && sc.Licence != licence {
+		sc.Licence = licence
+		s.dirty[sc] = true
+	}
+}
+
+// save writes any sidecars that have changed.
+func (s *store) save() error {
+	for _, sc := range s.Sidecars {
+		if !s.dirty[sc] {
+			continue
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false) // keep the <image> elements readable
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(sc); err != nil {
+			return fmt.Errorf("failed to encode sidecar: %w", err)
+		}
+		filename := filepath.Join(s.Dir, strings.TrimSuffix(sc.Image, filepath.Ext(sc.Image))+".json")
+		if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write sidecar: %w", err)
+		}
+	}
+	clear(s.dirty)
+	return nil
+}
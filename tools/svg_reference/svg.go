@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+)
+
+const (
+	inkscapeNS = "http://www.inkscape.org/namespaces/inkscape"
+	xlinkNS    = "http://www.w3.org/1999/xlink"
+
+	referenceLayerLabel = "Reference Artwork"
+)
+
+// mimeTypes are the image types found in reference artwork, and the extension they're stored with.
+var mimeTypes = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+}
+
+// imageElement is an <image> element found in an SVG.
+type imageElement struct {
+	Start, End int64          // the whole element
+	Href       string         // decoded value of its href
+	RawHref    svgdoc.RawAttr // where the href is, relative to Start
+	Hidden     bool
+}
+
+// scan is what was found in an SVG.
+type scan struct {
+	Images []imageElement
+
+	// ReferenceLayer is the raw start tag of the "Reference Artwork" layer, if there is one
+	ReferenceLayer    []byte
+	ReferenceLayerEnd int64 // where the start tag ends
+}
+
+// scanSVG finds the <image> elements in an SVG, and the reference layer.
+func scanSVG(data []byte) (*scan, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	res := &scan{}
+	hidden := []bool{false}
+	var open []*imageElement // images that have started, by depth
+
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xml parse error: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			h := hidden[len(hidden)-1] || svgdoc.Hidden(t.Attr)
+			hidden = append(hidden, h)
+			end := dec.InputOffset()
+			raw := data[start:end]
+
+			var img *imageElement
+			switch t.Name.Local {
+			case "image":
+				attrs := svgdoc.ScanRawAttrs(raw)
+				for _, a := range t.Attr {
+					if a.Name.Local != "href" || (a.Name.Space != "" && a.Name.Space != xlinkNS) {
+						continue
+					}
+					name := "href"
+					if a.Name.Space == xlinkNS {
+						name = rawPrefixed(attrs, "href")
+					}
+					img = &imageElement{Start: start, Href: a.Value, RawHref: attrs[name], Hidden: h}
+				}
+			case "g":
+				if res.ReferenceLayer == nil && isReferenceLayer(t) {
+					res.ReferenceLayer = raw
+					res.ReferenceLayerEnd = end
+				}
+			}
+			open = append(open, img)
+
+		case xml.EndElement:
+			hidden = hidden[:len(hidden)-1]
+			img := open[len(open)-1]
+			open = open[:len(open)-1]
+			if img != nil {
+				img.End = dec.InputOffset()
+				res.Images = append(res.Images, *img)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// rawPrefixed finds the name a namespaced attribute was written with, eg: "xlink:href".
+func rawPrefixed(attrs map[string]svgdoc.RawAttr, local string) string {
+	for name := range attrs {
+		if strings.HasSuffix(name, ":"+local) {
+			return name
+		}
+	}
+	return local
+}
+
+func isReferenceLayer(t xml.StartElement) bool {
+	var layer, label bool
+	for _, a := range t.Attr {
+		if a.Name.Space != inkscapeNS {
+			continue
+		}
+		switch a.Name.Local {
+		case "groupmode":
+			layer = a.Value == "layer"
+		case "label":
+			label = strings.TrimSpace(a.Value) == referenceLayerLabel
+		}
+	}
+	return layer && label
+}
+
+// encodeDataURL returns a base64 data: URL for a reference image file.
+func encodeDataURL(filename string, data []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for mimeType, e := range mimeTypes {
+		if e == ext {
+			return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported reference image type %q", ext)
+}
+
+// frameSuffixRe matches the frame number of an animated silhouette's filename, eg: the "-2" of "B06-2"
+var frameSuffixRe = regexp.MustCompile(`-\d+$`)
+
+// designatorOf returns the designator a silhouette belongs to, from its filename.
+func designatorOf(svgPath string) string {
+	stem := strings.TrimSuffix(filepath.Base(svgPath), filepath.Ext(svgPath))
+	return frameSuffixRe.ReplaceAllString(stem, "")
+}
+
+// replaceHref returns an edit setting an image's href to v.
+func (img imageElement) replaceHref(v string) svgdoc.Edit {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(v))
+	return svgdoc.Edit{
+		Start: img.Start + int64(img.RawHref.ValueStart),
+		End:   img.Start + int64(img.RawHref.ValueEnd),
+		Text:  buf.Bytes(),
+	}
+}
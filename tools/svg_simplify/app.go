@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+
+	"github.com/plane-watch/pw-silhouettes/internal/inkscape"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/plane-watch/pw-silhouettes/internal/svgpath"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+type options struct {
+	Tolerance      float64
+	Precision      int
+	InkscapeBinary string
+	PixelThreshold int
+	MaxDiffPixels  int
+	DryRun         bool
+}
+
+// result is what happened to a file.
+type result int
+
+const (
+	unchanged  result = iota // nothing could be simplified
+	simplified               // simplified, and the render didn't change
+	rejected                 // simplified, but the render changed too much
+)
+
+func runApp(_ context.Context, cmd *cli.Command) error {
+
+	opts := options{
+		Tolerance:      cmd.Float("tolerance"),
+		Precision:      int(cmd.Int("precision")),
+		InkscapeBinary: cmd.String("inkscape_binary"),
+		PixelThreshold: int(cmd.Int("pixel_threshold")),
+		MaxDiffPixels:  int(cmd.Int("max_diff_pixels")),
+		DryRun:         cmd.Bool("dry_run"),
+	}
+	if opts.Tolerance <= 0 {
+		return fmt.Errorf("--tolerance must be greater than 0")
+	}
+
+	files, err := svgdoc.Files(cmd.StringSlice("input"))
+	if err != nil {
+		return err
+	}
+
+	counts := map[result]int{}
+	for _, file := range files {
+		res, err := simplifyFile(file, opts)
+		if err != nil {
+			return fmt.Errorf("failed to simplify %s: %w", file, err)
+		}
+		counts[res]++
+	}
+
+	log.Info().
+		Int("files", len(files)).
+		Int("simplified", counts[simplified]).
+		Int("rejected", counts[rejected]).
+		Bool("dry_run", opts.DryRun).
+		Msg("done")
+	return nil
+}
+
+func simplifyFile(file string, opts options) (result, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return unchanged, fmt.Errorf("failed to read svg: %w", err)
+	}
+	paths, err := scanPaths(data)
+	if err != nil {
+		return unchanged, err
+	}
+
+	var edits []svgdoc.Edit
+	var before, after int
+	for _, p := range paths {
+		if p.PathEffect {
+			log.Warn().Str("file", file).Int("line", p.Line).Msg("skipping path with an Inkscape path effect")
+			continue
+		}
+		subpaths, err := svgpath.Parse(p.D)
+		if err != nil {
+			return unchanged, fmt.Errorf("line %d: invalid path data: %w", p.Line, err)
+		}
+
+		// the tolerance and precision are in px, so convert them to the path's user units
+		simple := svgpath.Simplify(subpaths, opts.Tolerance/p.Scale)
+		n, m := nodes(subpaths), nodes(simple)
+		if m >= n {
+			continue
+		}
+		before += n
+		after += m
+		edits = append(edits, p.setD(svgpath.Format(simple, svgpath.Decimals(p.Scale, opts.Precision)))...)
+	}
+	if len(edits) == 0 {
+		log.Debug().Str("file", file).Msg("nothing to simplify")
+		return unchanged, nil
+	}
+	out := svgdoc.Apply(data, edits)
+
+	diff, err := renderDiff(file, out, opts)
+	if err != nil {
+		return unchanged, err
+	}
+	l := log.With().Str("file", file).Int("before", before).Int("after", after).Int("diff_pixels", diff).Logger()
+	if diff > opts.MaxDiffPixels {
+		l.Warn().Msg("rejected simplification, as it changes the rendered silhouette (try a lower --tolerance)")
+		return rejected, nil
+	}
+
+	if !opts.DryRun {
+		if err := svgdoc.WriteFileAtomic(file, out); err != nil {
+			return unchanged, fmt.Errorf("failed to write svg: %w", err)
+		}
+	}
+	l.Info().Msg("simplified svg")
+	return simplified, nil
+}
+
+func nodes(subpaths []svgpath.Subpath) int {
+	var n int
+	for _, sp := range subpaths {
+		n += sp.Nodes()
+	}
+	return n
+}
+
+// renderDiff renders the original file and its simplified content, returning how many pixels differ.
+func renderDiff(file string, simplified []byte, opts options) (int, error) {
+	// alongside the original, so anything it links to relatively still resolves
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*.svg")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary svg: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(simplified); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to write temporary svg: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write temporary svg: %w", err)
+	}
+
+	a, err := inkscape.Render(opts.InkscapeBinary, file, 0)
+	if err != nil {
+		return 0, err
+	}
+	b, err := inkscape.Render(opts.InkscapeBinary, tmp.Name(), 0)
+	if err != nil {
+		return 0, err
+	}
+	return diffPixels(a, b, opts.PixelThreshold), nil
+}
+
+// diffPixels counts the pixels where any channel differs by more than threshold (0-255).
+func diffPixels(a, b image.Image, threshold int) int {
+	ba, bb := a.Bounds(), b.Bounds()
+	if ba.Dx() != bb.Dx() || ba.Dy() != bb.Dy() {
+		return ba.Dx() * ba.Dy()
+	}
+
+	var n int
+	for y := 0; y < ba.Dy(); y++ {
+		for x := 0; x < ba.Dx(); x++ {
+			r1, g1, b1, a1 := a.At(ba.Min.X+x, ba.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+			for _, d := range []int{
+				int(r1>>8) - int(r2>>8),
+				int(g1>>8) - int(g2>>8),
+				int(b1>>8) - int(b2>>8),
+				int(a1>>8) - int(a2>>8),
+			} {
+				if d > threshold || -d > threshold {
+					n++
+					break
+				}
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:   "svg_simplify",
+	Usage:  "Simplify the visible paths of silhouette SVGs in place, checking the rendered result hasn't changed",
+	Action: runApp,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "input",
+			Usage: "SVG file, or directory of SVG files, to simplify",
+			Value: []string{"silhouettes/"},
+		},
+		&cli.FloatFlag{
+			Name:  "tolerance",
+			Usage: "Maximum distance in px that the simplified outline may deviate from the original",
+			Value: 0.1,
+		},
+		&cli.IntFlag{
+			Name:  "precision",
+			Usage: "Decimal places of a pixel to keep in the rewritten path data",
+			Value: 3,
+		},
+		&cli.StringFlag{
+			Name:     "inkscape_binary",
+			Aliases:  []string{"inkscape"},
+			Usage:    "Path to the inkscape v1+ binary, used to render each file before and after",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "pixel_threshold",
+			Usage: "Change in any colour channel (0-255) above which a rendered pixel counts as different",
+			Value: 64,
+		},
+		&cli.IntFlag{
+			Name:  "max_diff_pixels",
+			Usage: "Reject the simplification of a file if more than this many pixels of its 70px render are different",
+			Value: 0,
+		},
+		&cli.BoolFlag{
+			Name:  "dry_run",
+			Usage: "Report what would be simplified without writing any files",
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/plane-watch/pw-silhouettes/internal/svgpath"
+)
+
+const inkscapeNS = "http://www.inkscape.org/namespaces/inkscape"
+
+// nonRendered are elements whose content is only drawn when referenced, if at all.
+var nonRendered = map[string]bool{
+	"defs":     true,
+	"symbol":   true,
+	"clipPath": true,
+	"mask":     true,
+	"marker":   true,
+	"pattern":  true,
+}
+
+// visiblePath is a visible <path> found in an SVG.
+type visiblePath struct {
+	Line  int
+	Start int64  // where its start tag begins
+	Raw   []byte // the raw start tag
+	D     string
+	Scale float64 // pixels per user unit of its path data
+
+	// Inkscape path effects regenerate d from inkscape:original-d, so the path can't be edited directly
+	PathEffect bool
+}
+
+// scanPaths finds the visible paths of an SVG.
+func scanPaths(data []byte) ([]visiblePath, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	type frame struct {
+		hidden bool
+		scale  float64
+	}
+	stack := []frame{{scale: 1}}
+	var out []visiblePath
+
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xml parse error: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			parent := stack[len(stack)-1]
+			f := frame{
+				hidden: parent.hidden || svgdoc.Hidden(t.Attr) || nonRendered[t.Name.Local],
+				scale:  parent.scale,
+			}
+
+			var d string
+			var hasD, pathEffect bool
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "transform":
+					m, err := svgpath.ParseTransform(a.Value)
+					if err != nil {
+						line, _ := dec.InputPos()
+						return nil, fmt.Errorf("line %d: <%s> invalid transform: %w", line, t.Name.Local, err)
+					}
+					f.scale *= m.Scale()
+				case a.Name.Space == "" && a.Name.Local == "d":
+					d, hasD = a.Value, true
+				case a.Name.Space == inkscapeNS && (a.Name.Local == "original-d" || a.Name.Local == "path-effect"):
+					pathEffect = true
+				}
+			}
+
+			if len(stack) == 1 && t.Name.Local == "svg" {
+				var width, viewBox string
+				for _, a := range t.Attr {
+					if a.Name.Space == "" && a.Name.Local == "width" {
+						width = a.Value
+					}
+					if a.Name.Space == "" && a.Name.Local == "viewBox" {
+						viewBox = a.Value
+					}
+				}
+				f.scale *= svgdoc.PixelsPerUnit(width, viewBox)
+			}
+
+			if t.Name.Local == "path" && hasD && !f.hidden {
+				line, _ := dec.InputPos()
+				out = append(out, visiblePath{
+					Line:       line,
+					Start:      start,
+					Raw:        data[start:dec.InputOffset()],
+					D:          d,
+					Scale:      f.scale,
+					PathEffect: pathEffect,
+				})
+			}
+			stack = append(stack, f)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return out, nil
+}
+
+// setD returns the edits that replace a path's data, dropping Inkscape's record of its node types
+// (which would no longer match).
+func (p visiblePath) setD(d string) []svgdoc.Edit {
+	attrs := svgdoc.ScanRawAttrs(p.Raw)
+
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(d))
+	edits := []svgdoc.Edit{{
+		Start: p.Start + int64(attrs["d"].ValueStart),
+		End:   p.Start + int64(attrs["d"].ValueEnd),
+		Text:  buf.Bytes(),
+	}}
+
+	for name, a := range attrs {
+		if !strings.HasSuffix(name, ":nodetypes") {
+			continue
+		}
+		edits = append(edits, svgdoc.RemoveAttr(p.Raw, p.Start, a))
+	}
+	return edits
+}
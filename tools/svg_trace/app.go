@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/plane-watch/pw-silhouettes/internal/refimage"
+	"github.com/plane-watch/pw-silhouettes/internal/svgcheck"
+	"github.com/plane-watch/pw-silhouettes/internal/svgdoc"
+	"github.com/plane-watch/pw-silhouettes/internal/svgpath"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+const inkscapeNS = "http://www.inkscape.org/namespaces/inkscape"
+
+// document is what we need to know about the SVG to add a layer to it.
+type document struct {
+	RootStart  int64  // where the root's start tag begins
+	RootRaw    []byte // the root's raw start tag
+	End        int64  // where to insert before the root's end tag
+	EndOwnLine bool   // the root's end tag is on a line of its own, and End is the start of that line
+	Inkscape   string // prefix of the inkscape namespace, if declared on the root
+	IDs        map[string]bool
+	Visible    []string // labels of the visible top-level layers
+}
+
+func runApp(_ context.Context, cmd *cli.Command) error {
+
+	file := cmd.String("svg")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read svg: %w", err)
+	}
+	doc, err := scanDocument(data)
+	if err != nil {
+		return fmt.Errorf("invalid svg file: %w", err)
+	}
+	// the outline is styled as svg_check will check the file it's written to
+	target := cmd.String("output")
+	if target == "" {
+		target = file
+	}
+	profile, err := svgcheck.ProfileForSVG(cmd.String("config"), cmd.String("profile"), target)
+	if err != nil {
+		return err
+	}
+
+	ref, err := refimage.Load(file)
+	if errors.Is(err, refimage.ErrNoReference) {
+		return fmt.Errorf("no reference image found in the %q layer", "Reference Artwork")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load reference image: %w", err)
+	}
+	toRoot, ok := ref.RootToCanvas.Invert()
+	if !ok {
+		return fmt.Errorf("invalid root viewBox")
+	}
+
+	zoom := max(int(cmd.Int("zoom")), 1)
+	grid := ref.Resample(zoom)
+	px := func(name string) int { return int(cmd.Float(name)*float64(zoom) + 0.5) }
+	mask := refimage.Silhouette(grid, cmd.Float("threshold"), px("close"), px("open"))
+	if mask.Count() == 0 {
+		return fmt.Errorf("line %d: nothing stands out from the background of the reference image, try a lower --threshold", ref.Line)
+	}
+	if b := mask.BBox(); b.Min.X == 0 || b.Min.Y == 0 || b.Max.X == mask.W || b.Max.Y == mask.H {
+		log.Warn().Msg("the traced shape reaches the edge of the canvas: the aircraft may not fit, or may have merged with other artwork")
+	}
+
+	// grid pixels to canvas px to the root's user units, which the new layer uses as it has no transform
+	gridToRoot := toRoot.Mul(svgpath.Matrix{A: 1 / float64(zoom), D: 1 / float64(zoom)})
+	pxPerUnit := ref.RootToCanvas.Scale()
+
+	traced := polygon(smooth(contour(mask), int(cmd.Int("smooth"))), gridToRoot)
+	outline := svgpath.Simplify([]svgpath.Subpath{traced}, cmd.Float("tolerance")/pxPerUnit)
+	d := svgpath.Format(outline, svgpath.Decimals(pxPerUnit, int(cmd.Int("precision"))))
+
+	out := svgdoc.Apply(data, doc.addLayer(cmd.String("layer"), d, profile.OutlineStyle()))
+
+	dst := cmd.String("output")
+	if dst == "" {
+		if err := svgdoc.WriteFileAtomic(file, out); err != nil {
+			return fmt.Errorf("failed to write svg: %w", err)
+		}
+		dst = file
+	} else if err := os.WriteFile(dst, out, 0644); err != nil {
+		return fmt.Errorf("failed to write svg: %w", err)
+	}
+
+	if len(doc.Visible) > 0 {
+		log.Warn().
+			Strs("layers", doc.Visible).
+			Msg("there are other visible layers: hide or delete them once the traced outline has been checked, as only one is allowed")
+	}
+	log.Info().
+		Str("file", dst).
+		Int("reference_line", ref.Line).
+		Int("nodes", outline[0].Nodes()).
+		Msg("traced outline")
+	return nil
+}
+
+// scanDocument finds where the root element starts and ends, the ids in use, and the visible top-level layers.
+func scanDocument(data []byte) (*document, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	type frame struct {
+		hidden bool
+		layer  bool // in a layer
+	}
+	stack := []frame{{}}
+	doc := &document{IDs: map[string]bool{}}
+
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xml parse error: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			parent := stack[len(stack)-1]
+			f := frame{hidden: parent.hidden || svgdoc.Hidden(t.Attr), layer: parent.layer}
+
+			var mode, label string
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "id":
+					doc.IDs[a.Value] = true
+				case a.Name.Space == inkscapeNS && a.Name.Local == "groupmode":
+					mode = a.Value
+				case a.Name.Space == inkscapeNS && a.Name.Local == "label":
+					label = a.Value
+				}
+			}
+
+			if len(stack) == 1 {
+				doc.RootStart = start
+				doc.RootRaw = data[start:dec.InputOffset()]
+				for name, a := range svgdoc.ScanRawAttrs(doc.RootRaw) {
+					if prefix, ok := strings.CutPrefix(name, "xmlns:"); ok && a.Value == inkscapeNS {
+						doc.Inkscape = prefix
+					}
+				}
+			}
+			if t.Name.Local == "g" && mode == "layer" {
+				if !parent.layer && !f.hidden {
+					doc.Visible = append(doc.Visible, label)
+				}
+				f.layer = true
+			}
+			stack = append(stack, f)
+
+		case xml.EndElement:
+			if len(stack) == 2 {
+				doc.End = start
+				lineStart := int64(bytes.LastIndexByte(data[:start], '\n') + 1)
+				if lineStart > 0 && len(bytes.TrimSpace(data[lineStart:start])) == 0 {
+					doc.End, doc.EndOwnLine = lineStart, true
+				}
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if doc.RootRaw == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return doc, nil
+}
+
+// addLayer returns the edits that add a layer containing the outline as the last child of the root,
+// so it's drawn above everything else.
+func (doc *document) addLayer(label, d string, style map[string]string) []svgdoc.Edit {
+	var edits []svgdoc.Edit
+
+	prefix := doc.Inkscape
+	if prefix == "" {
+		prefix = "inkscape"
+		// declare the namespace just before the end of the root's start tag
+		end := len(doc.RootRaw) - 1
+		if end > 0 && doc.RootRaw[end-1] == '/' {
+			end--
+		}
+		at := doc.RootStart + int64(end)
+		edits = append(edits, svgdoc.Edit{Start: at, End: at, Text: []byte(` xmlns:` + prefix + `="` + inkscapeNS + `"`)})
+	}
+
+	attr := func(s string) string {
+		var buf bytes.Buffer
+		xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	}
+
+	var b strings.Builder
+	b.WriteString("  <g\n")
+	fmt.Fprintf(&b, "     %s:groupmode=\"layer\"\n", prefix)
+	fmt.Fprintf(&b, "     id=\"%s\"\n", doc.newID("layer"))
+	fmt.Fprintf(&b, "     %s:label=\"%s\">\n", prefix, attr(label))
+	b.WriteString("    <path\n")
+	fmt.Fprintf(&b, "       style=\"%s\"\n", svgdoc.SetStyleProperties("", svgcheck.OutlineStyleKeys, style))
+	fmt.Fprintf(&b, "       d=\"%s\"\n", attr(d))
+	fmt.Fprintf(&b, "       id=\"%s\" />\n", doc.newID("path"))
+	b.WriteString("  </g>\n")
+
+	text := b.String()
+	if !doc.EndOwnLine {
+		text = "\n" + text
+	}
+	edits = append(edits, svgdoc.Edit{Start: doc.End, End: doc.End, Text: []byte(text)})
+	return edits
+}
+
+// newID returns an id that isn't in use yet, made of the stem and a number.
+func (doc *document) newID(stem string) string {
+	for n := 1; ; n++ {
+		id := stem + strconv.Itoa(n)
+		if !doc.IDs[id] {
+			doc.IDs[id] = true
+			return id
+		}
+	}
+}
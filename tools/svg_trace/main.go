@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+var cmd = &cli.Command{
+	Name:   "svg_trace",
+	Usage:  "Trace a first draft of the outline from the reference artwork, into a new layer",
+	Action: runApp,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "svg",
+			Usage:    "Path to the SVG file with the reference artwork",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "Write the result to this file, instead of replacing --svg",
+		},
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "Path to the svg_check config file, whose profile sets the outline's style (default: the first svg_check.json in the svg's directory or above it)",
+		},
+		&cli.StringFlag{
+			Name:  "profile",
+			Usage: "Name of the svg_check profile to style the outline with (default: chosen by the config file)",
+		},
+		&cli.IntFlag{
+			Name:  "zoom",
+			Usage: "Pixels per canvas px to trace the reference artwork at",
+			Value: 8,
+		},
+		&cli.FloatFlag{
+			Name:  "threshold",
+			Usage: "How far a colour must be from the background to count as part of the aircraft, from 0 to 1",
+			Value: 0.15,
+		},
+		&cli.FloatFlag{
+			Name:  "close",
+			Usage: "Gaps in the artwork up to this many px across are closed before tracing",
+			Value: 1,
+		},
+		&cli.FloatFlag{
+			Name:  "open",
+			Usage: "Parts of the traced shape up to this many px across, such as dimension lines touching it, are removed",
+			Value: 0.25,
+		},
+		&cli.IntFlag{
+			Name:  "smooth",
+			Usage: "Passes of smoothing over the traced pixel edges",
+			Value: 4,
+		},
+		&cli.FloatFlag{
+			Name:  "tolerance",
+			Usage: "Maximum distance in px the fitted curves may stray from the smoothed trace",
+			Value: 0.25,
+		},
+		&cli.IntFlag{
+			Name:  "precision",
+			Usage: "Decimal places of a pixel to keep in the written path data",
+			Value: 3,
+		},
+		&cli.StringFlag{
+			Name:  "layer",
+			Usage: "Label of the layer to add",
+			Value: "Outline",
+		},
+	},
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/plane-watch/pw-silhouettes/internal/raster"
+	"github.com/plane-watch/pw-silhouettes/internal/svgpath"
+)
+
+// heading is a direction along the pixel edges, with y pointing down.
+type heading struct{ dx, dy int }
+
+var (
+	east  = heading{1, 0}
+	south = heading{0, 1}
+	west  = heading{-1, 0}
+	north = heading{0, -1}
+)
+
+func (h heading) right() heading { return heading{-h.dy, h.dx} }
+func (h heading) left() heading  { return heading{h.dy, -h.dx} }
+
+// ahead returns the pixels either side of the edge leaving corner (x,y) in direction h: the one
+// on the right, then the one on the left.
+func (h heading) ahead(x, y int) (rx, ry, lx, ly int) {
+	switch h {
+	case east:
+		return x, y, x, y - 1
+	case south:
+		return x - 1, y, x, y
+	case west:
+		return x - 1, y - 1, x - 1, y
+	default: // north
+		return x, y - 1, x - 1, y - 1
+	}
+}
+
+// contour follows the outside edge of a 4-connected shape with no holes (such as from
+// refimage.Silhouette), returning the midpoint of each pixel edge along it, clockwise on screen.
+// Taking the midpoints rather than the corners turns single-pixel steps into diagonals.
+func contour(m *raster.Mask) []svgpath.Point {
+	// start at the top left corner of the topmost, then leftmost, pixel, heading along its top edge
+	start := -1
+	for i, on := range m.On {
+		if on {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+	x0, y0 := start%m.W, start/m.W
+
+	var pts []svgpath.Point
+	x, y, h := x0, y0, east
+	for {
+		pts = append(pts, svgpath.Point{X: float64(x) + float64(h.dx)/2, Y: float64(y) + float64(h.dy)/2})
+		x, y = x+h.dx, y+h.dy
+
+		// keep the shape on the right: turn right if there's nothing ahead, and left if the shape
+		// carries on round to the left. Pixels only touching diagonally aren't part of the shape.
+		rx, ry, lx, ly := h.ahead(x, y)
+		switch {
+		case !m.At(rx, ry):
+			h = h.right()
+		case m.At(lx, ly):
+			h = h.left()
+		}
+		if x == x0 && y == y0 && h == east {
+			return pts
+		}
+	}
+}
+
+// smooth evens out what's left of the pixel staircase by repeatedly moving each point of the closed
+// outline towards the average of its neighbours.
+func smooth(pts []svgpath.Point, passes int) []svgpath.Point {
+	n := len(pts)
+	if n < 3 {
+		return pts
+	}
+	for range passes {
+		out := make([]svgpath.Point, n)
+		for i, p := range pts {
+			prev, next := pts[(i+n-1)%n], pts[(i+1)%n]
+			out[i] = svgpath.Point{X: (prev.X + 2*p.X + next.X) / 4, Y: (prev.Y + 2*p.Y + next.Y) / 4}
+		}
+		pts = out
+	}
+	return pts
+}
+
+// polygon returns a closed subpath through the points, after transforming them.
+func polygon(pts []svgpath.Point, m svgpath.Matrix) svgpath.Subpath {
+	sp := svgpath.Subpath{Start: m.Apply(pts[0]), Closed: true}
+	prev := sp.Start
+	for _, p := range pts[1:] {
+		q := m.Apply(p)
+		sp.Segments = append(sp.Segments, svgpath.Segment{Cmd: 'L', Start: prev, End: q})
+		prev = q
+	}
+	return sp
+}